@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Access roles on a task or a list.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+// roles is the ordered list of roles, each role grants the ones before.
+var roles = []string{"", RoleViewer, RoleEditor, RoleOwner}
+
+// ErrForbidden is returned when the role of the user is not enough.
+var ErrForbidden = errors.New("permission denied")
+
+// ErrUnknownUser is returned when sharing with a user who doesn't exist.
+var ErrUnknownUser = errors.New("unknown user")
+
+// Share grant a role on a task or a list to a user. A role on a list applies
+// to all its tasks.
+type Share struct {
+	UserID string `bson:"user" jsonapi:"primary,share"`
+	Role   string `bson:"role" validate:"required,oneof=viewer editor owner" jsonapi:"attr,role"`
+}
+
+func init() {
+	resourceTypes["share"] = Share{}
+}
+
+// Validate checks attributes's integrity.
+func (sh *Share) Validate() error {
+	err := validator.New().Struct(sh)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// roleRank return the rank of a role, 0 is no access.
+func roleRank(role string) int {
+	for i, r := range roles {
+		if r == role {
+			return i
+		}
+	}
+	return 0
+}
+
+// grants checks the role grants the required role.
+func grants(role string, required string) bool {
+	return roleRank(role) >= roleRank(required)
+}
+
+// maxRole return the highest of the roles.
+func maxRole(a string, b string) string {
+	if roleRank(b) > roleRank(a) {
+		return b
+	}
+	return a
+}
+
+// sharedRole return the role of the user in the shares.
+func sharedRole(shares []*Share, user string) string {
+	role := ""
+	for _, sh := range shares {
+		if sh.UserID == user {
+			role = maxRole(role, sh.Role)
+		}
+	}
+	return role
+}
+
+// accessFields is the task fields the role is derived from.
+var accessFields = bson.M{"sid": 1, "createdBy": 1, "assignee": 1, "shares": 1, "list": 1}
+
+// visibleLists return the ID of the lists the user created or is shared.
+func visibleLists(db *mgo.Database, user string) ([]string, error) {
+	var lists []*List
+	q := bson.M{"$or": []bson.M{{"createdBy": user}, {"shares.user": user}}}
+	if err := db.C("lists").Find(q).Select(bson.M{"sid": 1}).All(&lists); err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, l := range lists {
+		ids = append(ids, l.SID)
+	}
+	return ids, nil
+}
+
+// visibleTo restrict the query to the tasks the user can view: the tasks it
+// created, is assigned to or is shared, directly or by their list. An empty
+// user sees every task.
+func visibleTo(db *mgo.Database, bq bson.M, user string) (bson.M, error) {
+	if user == "" {
+		return bq, nil
+	}
+	lists, err := visibleLists(db, user)
+	if err != nil {
+		return nil, fmt.Errorf("can't to find the shared lists (%v)", err)
+	}
+	or := []bson.M{{"createdBy": user}, {"assignee": user}, {"shares.user": user}, {"list": bson.M{"$in": lists}}}
+	return bson.M{"$and": []bson.M{bq, {"$or": or}}}, nil
+}
+
+// listRole return the role of the user on the list, the creator owns it. An
+// empty user owns every list.
+func listRole(l *List, user string) string {
+	if user == "" || l.CreatedByID == user {
+		return RoleOwner
+	}
+	return sharedRole(l.Shares, user)
+}
+
+// taskRole return the role of the user on the task, the highest of the
+// creator as owner, the assignee as editor and the shares of the task and
+// of its list. The task needs the accessFields.
+func taskRole(db *mgo.Database, t *Task, user string) (string, error) {
+	if user == "" || t.CreatedByID == user {
+		return RoleOwner, nil
+	}
+
+	role := sharedRole(t.Shares, user)
+	if t.AssigneeID == user {
+		role = maxRole(role, RoleEditor)
+	}
+	if t.ListID != "" {
+		l := &List{}
+		if err := db.C("lists").FindId(bson.ObjectIdHex(t.ListID)).Select(bson.M{"createdBy": 1, "shares": 1}).One(l); err != nil && err != mgo.ErrNotFound {
+			return "", err
+		}
+		role = maxRole(role, listRole(l, user))
+	}
+	return role, nil
+}
+
+// TaskRole return the role of the user on a task, mgo.ErrNotFound when the
+// task doesn't exist.
+func TaskRole(tenant string, user string, id string) (string, error) {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return "", mgo.ErrNotFound
+	}
+
+	t := &Task{}
+	if err := c.FindId(bson.ObjectIdHex(id)).Select(accessFields).One(t); err != nil {
+		return "", err
+	}
+	return taskRole(c.Database, t, user)
+}
+
+// requireTaskRoles return ErrForbidden unless the user have the role on every
+// task, including the tasks not visible to the user. The unknown tasks are
+// left to the caller.
+func requireTaskRoles(c *mgo.Collection, user string, ids []string, required string) error {
+	if user == "" || len(ids) == 0 {
+		return nil
+	}
+	var tasks []*Task
+	if err := c.Find(bson.M{"sid": bson.M{"$in": ids}}).Select(accessFields).All(&tasks); err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		role, err := taskRole(c.Database, t, user)
+		if err != nil {
+			return err
+		}
+		if !grants(role, required) {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+// ListRole return the role of the user on a list, mgo.ErrNotFound when the
+// list doesn't exist.
+func ListRole(tenant string, user string, id string) (string, error) {
+	if !bson.IsObjectIdHex(id) {
+		return "", mgo.ErrNotFound
+	}
+	l, err := SelectList(tenant, id)
+	if err != nil {
+		return "", err
+	}
+	return listRole(l, user), nil
+}
+
+// share replace the role of the user in the shares of a document, a share
+// without role only remove it. The changes of the shares of a task are
+// recorded in the audit as done by the actor.
+func share(c *mgo.Collection, actor string, id string, sh *Share) error {
+	oid := bson.ObjectIdHex(id)
+	before := bson.M{}
+	if err := c.FindId(oid).One(&before); err != nil {
+		return err
+	}
+	if err := c.UpdateId(oid, bson.M{"$pull": bson.M{"shares": bson.M{"user": sh.UserID}}}); err != nil {
+		return err
+	}
+	if sh.Role != "" {
+		if err := c.UpdateId(oid, bson.M{"$push": bson.M{"shares": sh}}); err != nil {
+			return err
+		}
+	}
+
+	if c.Name == "tasks" {
+		return auditTask(c, AuditUpdate, actor, before, oid)
+	}
+	return nil
+}
+
+// ShareTask grant a role on a task visible to the user, only the owners of
+// the task can share it. The change is recorded in the audit as done by the
+// actor.
+func ShareTask(tenant string, user string, actor string, id string, sh *Share) error {
+	return shareAs(tenant, user, actor, id, sh, "tasks", TaskRole)
+}
+
+// UnshareTask revoke the role of a user on a task.
+func UnshareTask(tenant string, user string, actor string, id string, target string) error {
+	return shareAs(tenant, user, actor, id, &Share{UserID: target}, "tasks", TaskRole)
+}
+
+// ShareList grant a role on a list and its tasks, only the owners of the
+// list can share it.
+func ShareList(tenant string, user string, id string, sh *Share) error {
+	return shareAs(tenant, user, user, id, sh, "lists", ListRole)
+}
+
+// UnshareList revoke the role of a user on a list.
+func UnshareList(tenant string, user string, id string, target string) error {
+	return shareAs(tenant, user, user, id, &Share{UserID: target}, "lists", ListRole)
+}
+
+// shareAs checks the user owns the document then grant the share, a share
+// without role is revoked.
+func shareAs(tenant string, user string, actor string, id string, sh *Share, collection string, role func(tenant string, user string, id string) (string, error)) error {
+	r, err := role(tenant, user, id)
+	if err != nil {
+		return err
+	}
+	if r == "" {
+		return mgo.ErrNotFound
+	}
+	if !grants(r, RoleOwner) {
+		return ErrForbidden
+	}
+
+	// Get the database connection.
+	s, c, err := getCollection(tenant, collection)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if sh.Role == "" {
+		return share(c, actor, id, sh)
+	}
+	if !bson.IsObjectIdHex(sh.UserID) {
+		return ErrUnknownUser
+	}
+	if _, err := SelectUser(tenant, sh.UserID); err == mgo.ErrNotFound {
+		return ErrUnknownUser
+	} else if err != nil {
+		return err
+	}
+	return share(c, actor, id, sh)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGrants(t *testing.T) {
+	cases := []struct {
+		role     string
+		required string
+		expected bool
+	}{
+		{RoleOwner, RoleEditor, true},
+		{RoleEditor, RoleEditor, true},
+		{RoleViewer, RoleEditor, false},
+		{"", RoleViewer, false},
+		{"unknown", RoleViewer, false},
+	}
+	for _, c := range cases {
+		if got := grants(c.role, c.required); got != c.expected {
+			t.Errorf("grants(%q, %q) : expected %v, got %v", c.role, c.required, c.expected, got)
+		}
+	}
+}
+
+func TestListRole(t *testing.T) {
+	l := &List{CreatedByID: "alice", Shares: []*Share{{UserID: "bob", Role: RoleViewer}}}
+
+	if r := listRole(l, "alice"); r != RoleOwner {
+		t.Errorf("expected the creator to be %v, got %v", RoleOwner, r)
+	}
+	if r := listRole(l, "bob"); r != RoleViewer {
+		t.Errorf("expected the shared user to be %v, got %v", RoleViewer, r)
+	}
+	if r := listRole(l, "carol"); r != "" {
+		t.Errorf("expected no role, got %v", r)
+	}
+}
+
+func TestShareTaskViewer(t *testing.T) {
+	alice := createUserOrFatal(t, "alice share task")
+	bob := createUserOrFatal(t, "bob share task")
+
+	task := newTaskOrFatal(t, "test share task")
+	task.CreatedByID = alice.SID
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := ShareTask("", bob.SID, bob.SID, task.SID, &Share{UserID: bob.SID, Role: RoleOwner}); err == nil {
+		t.Errorf("expected an error sharing a task not visible")
+	}
+	if err := ShareTask("", alice.SID, alice.SID, task.SID, &Share{UserID: bob.SID, Role: RoleViewer}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if r, _ := SelectTaskAs("", bob.SID, task.SID, nil); !r.ID.Valid() {
+		t.Errorf("expected the shared task to be visible to bob")
+	}
+	task.Title = "renamed by bob"
+	if err := task.UpdateAs("", bob.SID); err != ErrForbidden {
+		t.Errorf("expected error %v, got %v", ErrForbidden, err)
+	}
+
+	if err := UnshareTask("", alice.SID, alice.SID, task.SID, bob.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if r, _ := SelectTaskAs("", bob.SID, task.SID, nil); r.ID.Valid() {
+		t.Errorf("expected the task to be hidden to bob")
+	}
+}
+
+func TestShareListTasks(t *testing.T) {
+	alice := createUserOrFatal(t, "alice share list")
+	bob := createUserOrFatal(t, "bob share list")
+
+	list, err := NewList("test share list")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	list.CreatedByID = alice.SID
+	if err := list.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	task := newTaskOrFatal(t, "test share list task")
+	task.ListID = list.SID
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := ShareList("", alice.SID, list.SID, &Share{UserID: bob.SID, Role: RoleEditor}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if r, err := TaskRole("", bob.SID, task.SID); err != nil || r != RoleEditor {
+		t.Errorf("expected the role %v on the task, got %v (%v)", RoleEditor, r, err)
+	}
+	if err := DeleteTaskTreeAs("", bob.SID, bob.SID, task.SID, false); err != ErrForbidden {
+		t.Errorf("expected error %v, got %v", ErrForbidden, err)
+	}
+	if err := ShareList("", alice.SID, list.SID, &Share{UserID: "unknown", Role: RoleViewer}); err != ErrUnknownUser {
+		t.Errorf("expected error %v, got %v", ErrUnknownUser, err)
+	}
+}
+
+func TestIncludeHiddenRelations(t *testing.T) {
+	alice := createUserOrFatal(t, "alice hidden relations")
+	bob := createUserOrFatal(t, "bob hidden relations")
+
+	hidden := newTaskOrFatal(t, "test hidden relations blocker")
+	hidden.CreatedByID = alice.SID
+	if err := hidden.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	task := newTaskOrFatal(t, "test hidden relations task")
+	task.CreatedByID = alice.SID
+	task.BlockedBy = []*Task{hidden}
+	task.Parent = hidden
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := ShareTask("", alice.SID, alice.SID, task.SID, &Share{UserID: bob.SID, Role: RoleViewer}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	task.BlockedBy, task.Parent = nil, nil
+	if err := LoadTaskRelations("", bob.SID, []*Task{task}, []string{"blocked_by", "parent"}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(task.BlockedBy) != 0 || task.Parent != nil {
+		t.Errorf("expected the hidden task to be left out, got %v %v", task.BlockedBy, task.Parent)
+	}
+
+	tasks, err := DependencyOrder("", bob.SID, task.SID)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].SID != task.SID {
+		t.Errorf("expected only the shared task, got %v tasks", len(tasks))
+	}
+}
+
+func TestAttachToTaskRoles(t *testing.T) {
+	alice := createUserOrFatal(t, "alice attach roles")
+	bob := createUserOrFatal(t, "bob attach roles")
+
+	viewed := newTaskOrFatal(t, "test attach roles viewed")
+	viewed.CreatedByID = alice.SID
+	if err := viewed.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := ShareTask("", alice.SID, alice.SID, viewed.SID, &Share{UserID: bob.SID, Role: RoleViewer}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	hidden := newTaskOrFatal(t, "test attach roles hidden")
+	hidden.CreatedByID = alice.SID
+	if err := hidden.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	task := newTaskOrFatal(t, "test attach roles task")
+	task.CreatedByID = bob.SID
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	// A viewed task can block the task, but not be its parent.
+	task.Parent = viewed
+	if err := task.UpdateAs("", bob.SID); err != ErrForbidden {
+		t.Errorf("expected error %v for a viewed parent, got %v", ErrForbidden, err)
+	}
+	task.Parent = nil
+	task.BlockedBy = []*Task{hidden}
+	if err := task.UpdateAs("", bob.SID); err != ErrForbidden {
+		t.Errorf("expected error %v for a hidden blocker, got %v", ErrForbidden, err)
+	}
+	task.BlockedBy = []*Task{viewed}
+	if err := task.UpdateAs("", bob.SID); err != nil {
+		t.Errorf("unexpected error : %v", err)
+	}
+}
+
+func TestDeleteSubtasksRoles(t *testing.T) {
+	alice := createUserOrFatal(t, "alice delete subtasks")
+	bob := createUserOrFatal(t, "bob delete subtasks")
+
+	parent := newTaskOrFatal(t, "test delete subtasks parent")
+	parent.CreatedByID = alice.SID
+	if err := parent.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	child := newTaskOrFatal(t, "test delete subtasks child")
+	child.CreatedByID = bob.SID
+	child.Parent = parent
+	if err := child.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := ShareTask("", bob.SID, bob.SID, child.SID, &Share{UserID: alice.SID, Role: RoleViewer}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := DeleteTaskTreeAs("", alice.SID, alice.SID, parent.SID, true); err != ErrForbidden {
+		t.Errorf("expected error %v, got %v", ErrForbidden, err)
+	}
+	if r, _ := SelectTaskAs("", bob.SID, child.SID, nil); !r.ID.Valid() {
+		t.Errorf("expected the subtask to be kept")
+	}
+}
+
+func TestCompleteTaskViewedSubtasks(t *testing.T) {
+	alice := createUserOrFatal(t, "alice complete subtasks")
+	bob := createUserOrFatal(t, "bob complete subtasks")
+
+	openSubtasksPolicy = OpenSubtasksComplete
+	defer func() { openSubtasksPolicy = OpenSubtasksReject }()
+
+	parent := newTaskOrFatal(t, "test complete subtasks parent")
+	parent.CreatedByID = alice.SID
+	if err := parent.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	child := newTaskOrFatal(t, "test complete subtasks child")
+	child.CreatedByID = bob.SID
+	child.Parent = parent
+	if err := child.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := ShareTask("", bob.SID, bob.SID, child.SID, &Share{UserID: alice.SID, Role: RoleViewer}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	parent.Done = true
+	if err := parent.UpdateAs("", alice.SID); err != ErrForbidden {
+		t.Errorf("expected error %v, got %v", ErrForbidden, err)
+	}
+	if r, _ := SelectTaskAs("", bob.SID, child.SID, nil); r.Done {
+		t.Errorf("expected the subtask to stay open")
+	}
+}
+
+func TestCountsHideTasks(t *testing.T) {
+	alice := createUserOrFatal(t, "alice hidden counts")
+	bob := createUserOrFatal(t, "bob hidden counts")
+	tag := createTagOrFatal(t, "test hidden counts tag")
+
+	parent := newTaskOrFatal(t, "test hidden counts parent")
+	parent.CreatedByID = bob.SID
+	if err := parent.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	hidden := newTaskOrFatal(t, "test hidden counts child")
+	hidden.CreatedByID = alice.SID
+	hidden.Parent = parent
+	hidden.Tags = []*Tag{tag}
+	if err := hidden.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := LoadSubtaskProgress("", bob.SID, []*Task{parent}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if parent.Subtasks != nil {
+		t.Errorf("expected no visible subtask, got %v", parent.Subtasks)
+	}
+
+	find, err := SelectTagAs("", bob.SID, tag.SID)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if find.Count != 0 {
+		t.Errorf("expected no visible task, got %v", find.Count)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// apiKeyPrefix start every API key, the key is tk_<prefix>_<secret>.
+const apiKeyPrefix = "tk_"
+
+// lastUsedPeriod is the precision of the last use of the keys, it limits the
+// writes of busy keys.
+const lastUsedPeriod = time.Minute
+
+// maxMintRetries is the number of prefixes drawn before giving up minting a
+// key, prefixes are unique to find the key from the credentials.
+const maxMintRetries = 5
+
+// APIKey is a secret key of a user, only its hash is stored. The key itself
+// is only returned when minted.
+type APIKey struct {
+	ID         bson.ObjectId `bson:"_id,omitempty"`
+	SID        string        `bson:"sid,omitempty" jsonapi:"primary,api-key"`
+	Prefix     string        `bson:"prefix" jsonapi:"attr,prefix"`
+	Hash       string        `bson:"hash"`
+	Key        string        `bson:"-" jsonapi:"attr,key,omitempty"`
+	UserID     string        `bson:"user" validate:"required" jsonapi:"attr,user_id"`
+	Tenant     string        `bson:"tenant,omitempty" jsonapi:"attr,tenant,omitempty"`
+	Scopes     []string      `bson:"scopes" validate:"required,dive,oneof=tasks:read tasks:write admin" jsonapi:"attr,scopes"`
+	CreatedAt  time.Time     `bson:"createdAt" jsonapi:"attr,created_at"`
+	ExpiresAt  *time.Time    `bson:"expiresAt,omitempty" jsonapi:"attr,expires_at,iso8601,omitempty"`
+	LastUsedAt *time.Time    `bson:"lastUsedAt,omitempty" jsonapi:"attr,last_used_at,iso8601,omitempty"`
+	RevokedAt  *time.Time    `bson:"revokedAt,omitempty" jsonapi:"attr,revoked_at,iso8601,omitempty"`
+}
+
+func init() {
+	resourceTypes["api-key"] = APIKey{}
+}
+
+// Validate checks attributes's integrity.
+func (k *APIKey) Validate() error {
+	err := validator.New().Struct(k)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// hashKey return the stored hash of a key. Keys are random so a fast hash is
+// enough.
+func hashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// randomHex return n random bytes hex encoded.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Mint generate the key of a user of the key tenant and persist its hash.
+// The keys of every tenant are kept in the default tenant to be found from
+// the credentials.
+func (k *APIKey) Mint() error {
+	if _, err := SelectUser(k.Tenant, k.UserID); err != nil {
+		return fmt.Errorf("unknown user %v (%v)", k.UserID, err)
+	}
+
+	// Get the database connection.
+	s, c, err := getCollection("", "apiKeys")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Keys are rarely minted, the index is cached by the driver.
+	if err := c.EnsureIndex(mgo.Index{Key: []string{"prefix"}, Unique: true}); err != nil {
+		return fmt.Errorf("can't to index the api keys (%v)", err)
+	}
+
+	k.CreatedAt = time.Now()
+	k.LastUsedAt = nil
+	k.RevokedAt = nil
+
+	// Draw another prefix when it is already used.
+	for i := 0; i < maxMintRetries; i++ {
+		if k.Prefix, err = randomHex(4); err != nil {
+			return err
+		}
+		secret, err := randomHex(32)
+		if err != nil {
+			return err
+		}
+		k.Key = apiKeyPrefix + k.Prefix + "_" + secret
+		k.Hash = hashKey(k.Key)
+
+		k.ID = bson.NewObjectId()
+		k.SID = k.ID.Hex()
+
+		err = c.Insert(k)
+		if err == nil {
+			return nil
+		}
+		if !mgo.IsDup(err) {
+			return fmt.Errorf("can't to persist the api key (%v)", err)
+		}
+	}
+	return fmt.Errorf("can't to persist the api key (no free prefix after %v tries)", maxMintRetries)
+}
+
+// SearchAPIKeys return all the API keys of a tenant, latest first.
+func SearchAPIKeys(tenant string) ([]*APIKey, error) {
+	// Get the database connection.
+	s, c, err := getCollection("", "apiKeys")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	keys := []*APIKey{}
+	if err := c.Find(bson.M{"tenant": tenantMatch(tenant)}).Sort("-createdAt").All(&keys); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey disable an API key, revoked keys are kept for reference.
+func RevokeAPIKey(tenant string, id string) error {
+	// Get the database connection.
+	s, c, err := getCollection("", "apiKeys")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return fmt.Errorf("id value is not valid (%v)", id)
+	}
+	return c.Update(bson.M{"_id": bson.ObjectIdHex(id), "tenant": tenantMatch(tenant), "revokedAt": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"revokedAt": time.Now()}})
+}
+
+// APIKeyAuth authenticate the API keys of the users.
+type APIKeyAuth struct{}
+
+// Authenticate find the key of the credentials and record its use.
+func (APIKeyAuth) Authenticate(r *http.Request) (*Identity, error) {
+	token := credentials(r)
+	if !strings.HasPrefix(token, apiKeyPrefix) {
+		return nil, ErrNoCredentials
+	}
+	parts := strings.SplitN(strings.TrimPrefix(token, apiKeyPrefix), "_", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Get the database connection.
+	s, c, err := getCollection("", "apiKeys")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	k := &APIKey{}
+	if err := c.Find(bson.M{"prefix": parts[0]}).One(k); err == mgo.ErrNotFound {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(hashKey(token)), []byte(k.Hash)) != 1 ||
+		k.RevokedAt != nil || (k.ExpiresAt != nil && k.ExpiresAt.Before(now)) {
+		return nil, ErrInvalidCredentials
+	}
+
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) > lastUsedPeriod {
+		if err := c.UpdateId(k.ID, bson.M{"$set": bson.M{"lastUsedAt": now}}); err != nil {
+			return nil, err
+		}
+	}
+	return &Identity{UserID: k.UserID, Tenant: k.Tenant, KeyID: k.SID, Scopes: k.Scopes}, nil
+}
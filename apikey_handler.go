@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+	mgo "gopkg.in/mgo.v2"
+)
+
+// MintAPIKeyAPI generate an API key for a user, the response is the only
+// time the key is shown.
+func MintAPIKeyAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	key := new(APIKey)
+	if err := populateModel(r.Body, w, key); err != nil {
+		return
+	}
+
+	if err := validateModel(key, w); err != nil {
+		return
+	}
+
+	// Mint the key in the tenant of the request.
+	key.Tenant = RequestTenant(r)
+	if err := key.Mint(); err != nil {
+		writeError(w, http.StatusInternalServerError, "Save Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, key)
+}
+
+// SearchAPIKeyAPI return all the API keys without their secret.
+func SearchAPIKeyAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	keys, err := SearchAPIKeys(RequestTenant(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, keys, len(keys))
+}
+
+// RevokeAPIKeyAPI disable an API key and return a 204 (no-content) response.
+func RevokeAPIKeyAPI(w http.ResponseWriter, r *http.Request) {
+
+	if err := RevokeAPIKey(RequestTenant(r), mux.Vars(r)["sid"]); err == mgo.ErrNotFound {
+		w.Header().Set("Content-Type", jsonapi.MediaType)
+		writeError(w, http.StatusNotFound, "Revoke Error", "api key not found or already revoked")
+		return
+	} else if err != nil {
+		w.Header().Set("Content-Type", jsonapi.MediaType)
+		writeError(w, http.StatusInternalServerError, "Revoke Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func mintAPIKeyOrFatal(t *testing.T, user *User, scopes ...string) *APIKey {
+	k := &APIKey{UserID: user.SID, Scopes: scopes}
+	if err := k.Validate(); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := k.Mint(); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return k
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	user := createUserOrFatal(t, "api key user")
+	k := mintAPIKeyOrFatal(t, user, ScopeTasksRead)
+
+	req, _ := http.NewRequest(http.MethodGet, "/task/", nil)
+	req.Header.Set("X-API-Key", k.Key)
+	id, err := (APIKeyAuth{}).Authenticate(req)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if id.UserID != user.SID {
+		t.Errorf("expected user %v, got %v", user.SID, id.UserID)
+	}
+
+	req.Header.Set("X-API-Key", k.Key+"x")
+	if _, err := (APIKeyAuth{}).Authenticate(req); err != ErrInvalidCredentials {
+		t.Errorf("expected error %v, got %v", ErrInvalidCredentials, err)
+	}
+
+	if err := RevokeAPIKey("", k.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	req.Header.Set("X-API-Key", k.Key)
+	if _, err := (APIKeyAuth{}).Authenticate(req); err != ErrInvalidCredentials {
+		t.Errorf("expected error %v for a revoked key, got %v", ErrInvalidCredentials, err)
+	}
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	user := createUserOrFatal(t, "expired key user")
+	expired := time.Now().Add(-time.Hour)
+	k := &APIKey{UserID: user.SID, Scopes: []string{ScopeTasksRead}, ExpiresAt: &expired}
+	if err := k.Mint(); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/task/", nil)
+	req.Header.Set("Authorization", "Bearer "+k.Key)
+	if _, err := (APIKeyAuth{}).Authenticate(req); err != ErrInvalidCredentials {
+		t.Errorf("expected error %v, got %v", ErrInvalidCredentials, err)
+	}
+}
+
+func TestAPIKeyPrefixUnique(t *testing.T) {
+	user := createUserOrFatal(t, "unique prefix user")
+	k := mintAPIKeyOrFatal(t, user, ScopeTasksRead)
+
+	s, c, err := getCollection("", "apiKeys")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer s.Close()
+
+	dup := *k
+	dup.ID = bson.NewObjectId()
+	if err := c.Insert(&dup); !mgo.IsDup(err) {
+		t.Errorf("expected a duplicate key error, got %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// maxAttachmentSize is the largest attachment in bytes.
+var maxAttachmentSize int64 = 10 << 20
+
+// attachmentTypes is the accepted content types, a type ending with / accept
+// all its subtypes.
+var attachmentTypes = []string{"image/", "text/plain", "application/pdf", "application/zip"}
+
+// ErrTooLarge is returned when an attachment exceeds maxAttachmentSize.
+var ErrTooLarge = errors.New("attachment is too large")
+
+// ErrTypeNotAllowed is returned when the content type of an attachment is not
+// accepted.
+var ErrTypeNotAllowed = errors.New("attachment content type is not accepted")
+
+// blobRetries and blobRetryDelay bound the wait for the removal of a content
+// stored again.
+var (
+	blobRetries    = 50
+	blobRetryDelay = 100 * time.Millisecond
+)
+
+// Attachment is a file attached to a task. The content is stored once by
+// hash in the blob store.
+type Attachment struct {
+	ID          bson.ObjectId `bson:"_id,omitempty"`
+	SID         string        `bson:"sid,omitempty" jsonapi:"primary,attachment"`
+	TaskID      string        `bson:"task" jsonapi:"attr,task_id"`
+	Filename    string        `bson:"filename" jsonapi:"attr,filename"`
+	ContentType string        `bson:"contentType" jsonapi:"attr,content_type"`
+	Size        int64         `bson:"size" jsonapi:"attr,size"`
+	Hash        string        `bson:"hash" jsonapi:"attr,sha256"`
+	CreatedAt   time.Time     `bson:"createdAt" jsonapi:"attr,created_at"`
+}
+
+// blobRefs is the attachments using a stored content, the content is removed
+// with the last one.
+type blobRefs struct {
+	Hash string `bson:"_id"`
+	// Refs is the ID of the attachments.
+	Refs []string `bson:"refs"`
+	// Deleting is set while the content is removed.
+	Deleting bool `bson:"deleting,omitempty"`
+}
+
+func init() {
+	resourceTypes["attachment"] = Attachment{}
+}
+
+// allowedType checks the content type is accepted.
+func allowedType(contentType string) bool {
+	contentType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	for _, t := range attachmentTypes {
+		if contentType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(contentType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveAttachment store the content of a file attached to a task. The content
+// is spooled to a temporary file to be hashed, sized and sniffed before
+// being stored, unless the same content is already stored.
+func SaveAttachment(tenant string, taskID string, filename string, r io.Reader) (*Attachment, error) {
+	task, err := SelectTaskFields(tenant, taskID, []string{})
+	if err != nil {
+		return nil, err
+	}
+	if !task.ID.Valid() {
+		return nil, mgo.ErrNotFound
+	}
+
+	tmp, err := ioutil.TempFile("", "attachment-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, maxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > maxAttachmentSize {
+		return nil, ErrTooLarge
+	}
+
+	// Sniff the content type.
+	head := make([]byte, 512)
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	n, _ := io.ReadFull(tmp, head)
+	contentType := http.DetectContentType(head[:n])
+	if !allowedType(contentType) {
+		return nil, ErrTypeNotAllowed
+	}
+
+	a := &Attachment{
+		TaskID:      taskID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		Hash:        hex.EncodeToString(h.Sum(nil)),
+		CreatedAt:   time.Now(),
+	}
+
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "attachments")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if err := checkStorageQuota(tenant, c, a.Size); err != nil {
+		return nil, err
+	}
+
+	a.ID = bson.NewObjectId()
+	a.SID = a.ID.Hex()
+	if err := retainBlob(tenant, c.Database, a.Hash, a.SID, tmp); err != nil {
+		return nil, fmt.Errorf("can't to store the attachment (%v)", err)
+	}
+	if err := c.Insert(a); err != nil {
+		releaseBlob(tenant, c.Database, a.Hash, a.SID)
+		return nil, fmt.Errorf("can't to persist the attachment (%v)", err)
+	}
+	return a, nil
+}
+
+// SelectAttachment find an attachment by ID.
+func SelectAttachment(tenant string, id string) (*Attachment, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "attachments")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, mgo.ErrNotFound
+	}
+
+	a := &Attachment{}
+	if err := c.FindId(bson.ObjectIdHex(id)).One(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SearchAttachments return the attachments of a task.
+func SearchAttachments(tenant string, taskID string) ([]*Attachment, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "attachments")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	attachments := []*Attachment{}
+	if err := c.Find(bson.M{"task": taskID}).Sort("createdAt").All(&attachments); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment remove an attachment.
+func DeleteAttachment(tenant string, id string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "attachments")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return mgo.ErrNotFound
+	}
+	return removeAttachments(tenant, c.Database, bson.M{"_id": bson.ObjectIdHex(id)})
+}
+
+// removeAttachments remove the attachments matching the query and the
+// content no other attachment use.
+func removeAttachments(tenant string, db *mgo.Database, query bson.M) error {
+	c := db.C("attachments")
+
+	var attachments []*Attachment
+	if err := c.Find(query).Select(bson.M{"sid": 1, "hash": 1}).All(&attachments); err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		if err := c.RemoveId(a.ID); err == mgo.ErrNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := releaseBlob(tenant, db, a.Hash, a.SID); err != nil {
+			return fmt.Errorf("can't to remove the attachment content (%v)", err)
+		}
+	}
+	return nil
+}
+
+// retainBlob reference the content by the attachment before the attachment
+// is persisted, the content is stored when no other attachment use it. The
+// references of a content being removed can't change, so the content is
+// stored again once removed.
+func retainBlob(tenant string, db *mgo.Database, hash string, id string, content io.ReadSeeker) error {
+	c := db.C("blobRefs")
+	for i := 0; i < blobRetries; i++ {
+		err := c.Update(bson.M{"_id": hash, "deleting": bson.M{"$ne": true}}, bson.M{"$addToSet": bson.M{"refs": id}})
+		if err != mgo.ErrNotFound {
+			return err
+		}
+
+		if err := c.Insert(&blobRefs{Hash: hash, Refs: []string{id}}); mgo.IsDup(err) {
+			time.Sleep(blobRetryDelay)
+			continue
+		} else if err != nil {
+			return err
+		}
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := blobs.Put(blobTenantKey(tenant, hash), content); err != nil {
+			releaseBlob(tenant, db, hash, id)
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("content %v is still being removed", hash)
+}
+
+// releaseBlob remove the reference of the attachment to the content, the
+// content is removed with its last reference.
+func releaseBlob(tenant string, db *mgo.Database, hash string, id string) error {
+	c := db.C("blobRefs")
+	if err := c.UpdateId(hash, bson.M{"$pull": bson.M{"refs": id}}); err == mgo.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Lock the references of an unused content while it is removed.
+	err := c.Update(bson.M{"_id": hash, "refs": bson.M{"$size": 0}, "deleting": bson.M{"$ne": true}}, bson.M{"$set": bson.M{"deleting": true}})
+	if err == mgo.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := blobs.Delete(blobTenantKey(tenant, hash)); err != nil {
+		c.UpdateId(hash, bson.M{"$unset": bson.M{"deleting": ""}})
+		return err
+	}
+	return c.RemoveId(hash)
+}
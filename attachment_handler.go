@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+	mgo "gopkg.in/mgo.v2"
+)
+
+// writeAttachmentError write the error of an attachment request.
+func writeAttachmentError(w http.ResponseWriter, title string, err error) {
+	switch err {
+	case ErrTooLarge:
+		writeError(w, http.StatusRequestEntityTooLarge, title, fmt.Sprintf("%v (max %v bytes)", err, maxAttachmentSize))
+	case ErrTypeNotAllowed:
+		writeError(w, http.StatusUnsupportedMediaType, title, err.Error())
+	case ErrQuotaExceeded:
+		writeError(w, http.StatusForbidden, title, err.Error())
+	case mgo.ErrNotFound:
+		writeError(w, http.StatusNotFound, title, "not found")
+	default:
+		writeError(w, http.StatusInternalServerError, title, err.Error())
+	}
+}
+
+// CreateAttachmentAPI attach the file of the multipart `file` field to the
+// task.
+func CreateAttachmentAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Upload Error"); err != nil {
+		return
+	}
+
+	// Leave room for the multipart envelope.
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Upload Error", "a multipart file field is required")
+		return
+	}
+	defer file.Close()
+
+	attachment, err := SaveAttachment(RequestTenant(r), mux.Vars(r)["sid"], header.Filename, file)
+	if err != nil {
+		writeAttachmentError(w, "Upload Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, attachment)
+}
+
+// SearchAttachmentAPI return the attachments of a task.
+func SearchAttachmentAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleViewer, "Search Error"); err != nil {
+		return
+	}
+
+	attachments, err := SearchAttachments(RequestTenant(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, attachments, len(attachments))
+}
+
+// DownloadAttachmentAPI write the content of an attachment, range requests
+// are supported.
+func DownloadAttachmentAPI(w http.ResponseWriter, r *http.Request) {
+	// The errors are written as jsonapi.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	attachment, err := SelectAttachment(RequestTenant(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeAttachmentError(w, "Download Error", err)
+		return
+	}
+	if err := authorizeTask(w, r, attachment.TaskID, RoleViewer, "Download Error"); err != nil {
+		return
+	}
+
+	blob, err := blobs.Open(blobTenantKey(RequestTenant(r), attachment.Hash))
+	if err != nil {
+		writeAttachmentError(w, "Download Error", err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.Filename))
+	w.Header().Set("ETag", `"`+attachment.Hash+`"`)
+	http.ServeContent(w, r, attachment.Filename, attachment.CreatedAt, blob)
+}
+
+// DeleteAttachmentAPI remove an attachment and return a 204 (no-content)
+// response.
+func DeleteAttachmentAPI(w http.ResponseWriter, r *http.Request) {
+
+	attachment, err := SelectAttachment(RequestTenant(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeAttachmentError(w, "Delete Error", err)
+		return
+	}
+	if err := authorizeTask(w, r, attachment.TaskID, RoleEditor, "Delete Error"); err != nil {
+		return
+	}
+
+	if err := DeleteAttachment(RequestTenant(r), attachment.SID); err != nil {
+		writeAttachmentError(w, "Delete Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
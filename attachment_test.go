@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestAllowedType(t *testing.T) {
+	for contentType, expected := range map[string]bool{
+		"image/png":                 true,
+		"text/plain; charset=utf-8": true,
+		"text/html; charset=utf-8":  false,
+		"application/octet-stream":  false,
+	} {
+		if allowedType(contentType) != expected {
+			t.Errorf("expected %v for %v", expected, contentType)
+		}
+	}
+}
+
+func TestSaveAttachmentDedup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer os.RemoveAll(dir)
+	blobs = &FileStore{Dir: dir}
+
+	task := createTaskOrFatal(t, "test attachment dedup")
+	a1, err := SaveAttachment("", task.SID, "a.txt", strings.NewReader("same content"))
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	a2, err := SaveAttachment("", task.SID, "b.txt", strings.NewReader("same content"))
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if a1.Hash != a2.Hash {
+		t.Errorf("expected the same hash, got %v and %v", a1.Hash, a2.Hash)
+	}
+
+	// The content is kept while an attachment use it.
+	if err := DeleteAttachment("", a1.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if _, err := blobs.Open(a2.Hash); err != nil {
+		t.Errorf("expected the content to be kept, got %v", err)
+	}
+
+	// Deleting the task remove the last attachment and the content.
+	if err := DeleteTask("", task.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if _, err := blobs.Open(a2.Hash); !os.IsNotExist(err) {
+		t.Errorf("expected the content to be removed, got %v", err)
+	}
+}
+
+func TestSaveAttachmentLimits(t *testing.T) {
+	task := createTaskOrFatal(t, "test attachment limits")
+
+	if _, err := SaveAttachment("", task.SID, "page.html", strings.NewReader("<html><body></body></html>")); err != ErrTypeNotAllowed {
+		t.Errorf("expected error %v, got %v", ErrTypeNotAllowed, err)
+	}
+
+	big := bytes.Repeat([]byte("a"), int(maxAttachmentSize)+1)
+	if _, err := SaveAttachment("", task.SID, "big.txt", bytes.NewReader(big)); err != ErrTooLarge {
+		t.Errorf("expected error %v, got %v", ErrTooLarge, err)
+	}
+}
+
+func TestAttachmentAPIRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer os.RemoveAll(dir)
+	blobs = &FileStore{Dir: dir}
+
+	task := createTaskOrFatal(t, "test attachment api")
+
+	m := mux.NewRouter()
+	m.HandleFunc("/task/{sid}/attachments", CreateAttachmentAPI)
+	m.HandleFunc("/attachments/{sid}/content", DownloadAttachmentAPI)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "notes.txt")
+	fw.Write([]byte("0123456789"))
+	mw.Close()
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/task/"+task.SID+"/attachments", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+
+	attachments, err := SearchAttachments("", task.SID)
+	if err != nil || len(attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %v (%v)", len(attachments), err)
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/attachments/"+attachments[0].SID+"/content", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "234" {
+		t.Errorf("Code : %v, Body : %v", rr.Code, rr.Body.String())
+	}
+}
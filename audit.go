@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Audit actions.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// auditIgnored is the task fields left out of the changes, they change with
+// every update or only track the pending work.
+var auditIgnored = []string{"_id", "sid", "updatedAt", "updatedBy", "nextPending"}
+
+// AuditChange is the value of a task field before and after a change, nil
+// when the field is unset.
+type AuditChange struct {
+	Before interface{} `bson:"before,omitempty" json:"before"`
+	After  interface{} `bson:"after,omitempty" json:"after"`
+}
+
+// AuditEntry record a change of a task by a user, the entries are never
+// changed nor removed.
+type AuditEntry struct {
+	ID      bson.ObjectId           `bson:"_id,omitempty"`
+	SID     string                  `bson:"sid,omitempty" jsonapi:"primary,audit-entry"`
+	TaskID  string                  `bson:"task" jsonapi:"attr,task_id"`
+	Action  string                  `bson:"action" jsonapi:"attr,action"`
+	UserID  string                  `bson:"user,omitempty" jsonapi:"attr,user_id,omitempty"`
+	At      time.Time               `bson:"at" jsonapi:"attr,at,iso8601"`
+	Changes map[string]*AuditChange `bson:"changes,omitempty" jsonapi:"attr,changes,omitempty"`
+}
+
+func init() {
+	resourceTypes["audit-entry"] = AuditEntry{}
+}
+
+// auditDiff return the fields changed between two stored versions of a task.
+func auditDiff(before bson.M, after bson.M) map[string]*AuditChange {
+	changes := map[string]*AuditChange{}
+	for k, v := range before {
+		if !contains(auditIgnored, k) && !reflect.DeepEqual(v, after[k]) {
+			changes[k] = &AuditChange{Before: v, After: after[k]}
+		}
+	}
+	for k, v := range after {
+		if _, ok := before[k]; !ok && !contains(auditIgnored, k) {
+			changes[k] = &AuditChange{After: v}
+		}
+	}
+	return changes
+}
+
+// recordAudit append the change of a task by the user to the audit trail of
+// the database. The task is nil before its creation and after its deletion.
+func recordAudit(db *mgo.Database, action string, user string, before bson.M, after bson.M) error {
+	doc := before
+	if doc == nil {
+		doc = after
+	}
+	sid, _ := doc["sid"].(string)
+
+	changes := auditDiff(before, after)
+	if action == AuditUpdate && len(changes) == 0 {
+		return nil
+	}
+
+	e := &AuditEntry{ID: bson.NewObjectId(), TaskID: sid, Action: action, UserID: user, At: time.Now(), Changes: changes}
+	e.SID = e.ID.Hex()
+	if err := db.C("audit").Insert(e); err != nil {
+		return fmt.Errorf("can't to record the %v of the task %v (%v)", action, sid, err)
+	}
+	return nil
+}
+
+// auditTask record the change of a persisted task by the user like
+// recordAudit, the task is read back after the change. A change which can't
+// be recorded is reverted, the created task is removed and the updated task
+// is restored before the error is returned.
+func auditTask(c *mgo.Collection, action string, user string, before bson.M, id bson.ObjectId) error {
+	after, err := taskDocument(c, id)
+	if err == nil {
+		err = recordAudit(c.Database, action, user, before, after)
+	}
+	if err == nil {
+		return nil
+	}
+
+	revert := c.RemoveId(id)
+	if before != nil {
+		revert = c.UpdateId(id, before)
+	}
+	if revert != nil {
+		log.Printf("audit: can't to revert the %v of the task %v (%v)", action, id.Hex(), revert)
+	}
+	return err
+}
+
+// auditedUpdate apply the update to the task matching the query and record
+// the change by the actor. mgo.ErrNotFound is returned when no task match.
+func auditedUpdate(c *mgo.Collection, actor string, query bson.M, update bson.M) error {
+	before := bson.M{}
+	if _, err := c.Find(query).Apply(mgo.Change{Update: update}, &before); err != nil {
+		return err
+	}
+	return auditTask(c, AuditUpdate, actor, before, before["_id"].(bson.ObjectId))
+}
+
+// auditedUpdateAll apply the update to every task matching the query, one by
+// one to record each change by the actor.
+func auditedUpdateAll(c *mgo.Collection, actor string, query bson.M, update bson.M) error {
+	var ids []bson.ObjectId
+	if err := c.Find(query).Distinct("_id", &ids); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		q := bson.M{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q["_id"] = id
+		if err := auditedUpdate(c, actor, q, update); err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskDocument return the stored version of a task.
+func taskDocument(c *mgo.Collection, id bson.ObjectId) (bson.M, error) {
+	doc := bson.M{}
+	if err := c.FindId(id).One(&doc); err != nil {
+		return nil, fmt.Errorf("can't find the task %v (%v)", id.Hex(), err)
+	}
+	return doc, nil
+}
+
+// AuditSearch holds the parameters of an audit search, the zero values match
+// every entry.
+type AuditSearch struct {
+	Tenant string
+	TaskID string
+	UserID string
+	Action string
+	// From and To is the range of the changes, To is excluded.
+	From  time.Time
+	To    time.Time
+	Page  int
+	Limit int
+}
+
+// Find return a page of the matching entries, the oldest first, and the
+// total count.
+func (as *AuditSearch) Find() ([]*AuditEntry, int, error) {
+	// Get the database connection.
+	s, c, err := getCollection(as.Tenant, "audit")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer s.Close()
+
+	query := bson.M{}
+	if as.TaskID != "" {
+		query["task"] = as.TaskID
+	}
+	if as.UserID != "" {
+		query["user"] = as.UserID
+	}
+	if as.Action != "" {
+		query["action"] = as.Action
+	}
+	at := bson.M{}
+	if !as.From.IsZero() {
+		at["$gte"] = as.From
+	}
+	if !as.To.IsZero() {
+		at["$lt"] = as.To
+	}
+	if len(at) > 0 {
+		query["at"] = at
+	}
+
+	q := c.Find(query)
+	n, err := q.Count()
+	if err != nil {
+		return nil, 0, fmt.Errorf("unexpected error %v", err)
+	}
+
+	entries := []*AuditEntry{}
+	if as.Page > 0 && as.Limit > 0 {
+		q = q.Skip((as.Page - 1) * as.Limit).Limit(as.Limit)
+	}
+	if err := q.Sort("at", "_id").All(&entries); err != nil {
+		return nil, 0, fmt.Errorf("unexpected error %v", err)
+	}
+	return entries, n, nil
+}
+
+// TaskHistory return every change of a task, the oldest first.
+func TaskHistory(tenant string, taskID string) ([]*AuditEntry, error) {
+	entries, _, err := (&AuditSearch{Tenant: tenant, TaskID: taskID}).Find()
+	return entries, err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+)
+
+// TaskHistoryAPI return the changes of a task visible to the user, the oldest
+// first.
+func TaskHistoryAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleViewer, "History Error"); err != nil {
+		return
+	}
+
+	entries, err := TaskHistory(RequestTenant(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "History Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, entries, len(entries))
+}
+
+// SearchAuditAPI return a page of the audit of the tenant filtered by task,
+// user, action and the from and to dates. The to date is included.
+func SearchAuditAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	// Get all params.
+	v := r.URL.Query()
+	search := &AuditSearch{Tenant: RequestTenant(r), TaskID: v.Get("task"), UserID: v.Get("user"), Page: 1, Limit: 50}
+
+	if action := v.Get("action"); action != "" {
+		if action != AuditCreate && action != AuditUpdate && action != AuditDelete {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", "action must be create, update or delete")
+			return
+		}
+		search.Action = action
+	}
+
+	if f := v.Get("from"); f != "" {
+		d, err := parseReportDate(f)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", "from must be a date")
+			return
+		}
+		search.From = d
+	}
+	if t := v.Get("to"); t != "" {
+		d, err := parseReportDate(t)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", "to must be a date")
+			return
+		}
+		search.To = d.AddDate(0, 0, 1)
+	}
+
+	for name, value := range map[string]*int{"page": &search.Page, "limit": &search.Limit} {
+		if p := v.Get(name); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 {
+				writeError(w, http.StatusBadRequest, "Query Parameter Error", name+" must be a positive number")
+				return
+			}
+			*value = n
+		}
+	}
+
+	entries, n, err := search.Find()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, entries, n)
+}
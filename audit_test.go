@@ -0,0 +1,175 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestAuditDiff(t *testing.T) {
+	before := bson.M{"sid": "1", "title": "old", "done": false, "priority": "low", "updatedAt": 1}
+	after := bson.M{"sid": "1", "title": "new", "done": false, "dueAt": 3, "updatedAt": 2}
+
+	changes := auditDiff(before, after)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %v", changes)
+	}
+	if c := changes["title"]; c.Before != "old" || c.After != "new" {
+		t.Errorf("unexpected title change %+v", c)
+	}
+	if c := changes["priority"]; c.Before != "low" || c.After != nil {
+		t.Errorf("unexpected priority change %+v", c)
+	}
+	if c := changes["dueAt"]; c.Before != nil || c.After != 3 {
+		t.Errorf("unexpected dueAt change %+v", c)
+	}
+}
+
+func TestTaskAudit(t *testing.T) {
+	alice := createUserOrFatal(t, "audit alice")
+
+	task := newTaskOrFatal(t, "test task audit")
+	task.CreatedByID = alice.SID
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	task.Title = "test task audit renamed"
+	task.UpdatedByID = alice.SID
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	// An update without change isn't recorded.
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := DeleteTaskTreeAs("", "", alice.SID, task.SID, false); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	entries, err := TaskHistory("", task.SID)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %v", len(entries))
+	}
+	for i, action := range []string{AuditCreate, AuditUpdate, AuditDelete} {
+		if entries[i].Action != action || entries[i].UserID != alice.SID {
+			t.Errorf("entry %v : expected %v by %v, got %v by %v", i, action, alice.SID, entries[i].Action, entries[i].UserID)
+		}
+	}
+	if c := entries[1].Changes["title"]; c == nil || c.Before != "test task audit" || c.After != "test task audit renamed" {
+		t.Errorf("unexpected title change %+v", c)
+	}
+
+	search := &AuditSearch{TaskID: task.SID, UserID: alice.SID, Action: AuditDelete, Page: 1, Limit: 10}
+	if _, n, err := search.Find(); err != nil || n != 1 {
+		t.Errorf("expected 1 delete, got %v (%v)", n, err)
+	}
+}
+
+func TestSearchAuditAPIParams(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/admin/audit", SearchAuditAPI)
+
+	for _, query := range []string{"action=rename", "from=yesterday", "limit=0"} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/admin/audit?"+query, nil)
+		m.ServeHTTP(rr, req)
+
+		// Test status code.
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%v : Code : %v, Error : %v", query, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestTaskWritesAudit(t *testing.T) {
+	alice := createUserOrFatal(t, "audit writes alice")
+	bob := createUserOrFatal(t, "audit writes bob")
+	tag := createTagOrFatal(t, "test audit writes tag")
+
+	task := newTaskOrFatal(t, "test audit writes")
+	task.CreatedByID = alice.SID
+	task.Tags = []*Tag{tag}
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := ShareTask("", alice.SID, alice.SID, task.SID, &Share{UserID: bob.SID, Role: RoleEditor}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if _, err := AddChecklistItem("", bob.SID, task.SID, &ChecklistItem{Text: "step"}, -1); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := DeleteTag("", alice.SID, tag.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := task.Delete("", bob.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	entries, err := TaskHistory("", task.SID)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	expected := []struct {
+		action string
+		user   string
+		field  string
+	}{
+		{AuditCreate, alice.SID, ""},
+		{AuditUpdate, alice.SID, "shares"},
+		{AuditUpdate, bob.SID, "checklist"},
+		{AuditUpdate, alice.SID, "tags"},
+		{AuditDelete, bob.SID, ""},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %v entries, got %v", len(expected), len(entries))
+	}
+	for i, e := range expected {
+		if entries[i].Action != e.action || entries[i].UserID != e.user {
+			t.Errorf("entry %v : expected %v by %v, got %v by %v", i, e.action, e.user, entries[i].Action, entries[i].UserID)
+		}
+		if e.field != "" && entries[i].Changes[e.field] == nil {
+			t.Errorf("entry %v : expected a change of %v, got %v", i, e.field, entries[i].Changes)
+		}
+	}
+}
+
+func TestTaskAuditFailureReverts(t *testing.T) {
+	task := createTaskOrFatal(t, "test task audit failure")
+
+	// A second entry of the task can't be recorded.
+	s, c, err := getCollection("", "audit")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer s.Close()
+	index := bson.M{"key": bson.M{"task": 1}, "name": "audit_failure", "unique": true, "partialFilterExpression": bson.M{"task": task.SID}}
+	if err := c.Database.Run(bson.D{{Name: "createIndexes", Value: "audit"}, {Name: "indexes", Value: []bson.M{index}}}, nil); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer c.DropIndexName("audit_failure")
+
+	task.Title = "test task audit failure renamed"
+	if err := task.Update(""); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+	if u := selectTaskOrFatal(t, task.SID); u.Title != "test task audit failure" {
+		t.Errorf("expected the update to be reverted, got %v", u.Title)
+	}
+
+	if err := DeleteTask("", task.SID); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+	if u := selectTaskOrFatal(t, task.SID); !u.ID.Valid() {
+		t.Errorf("expected the task to be kept")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/jsonapi"
+)
+
+// API scopes, tasks:write grants tasks:read and admin grants everything.
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeAdmin      = "admin"
+)
+
+// ErrNoCredentials is returned by an authenticator when the request has no
+// credentials it knows.
+var ErrNoCredentials = errors.New("authentication is required")
+
+// ErrInvalidCredentials is returned when the credentials are wrong, expired
+// or revoked.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// UserID is empty for the bootstrap admin key.
+	UserID string
+	// Tenant is the tenant of the credentials, the bootstrap admin key
+	// isn't bound to a tenant.
+	Tenant string
+	// KeyID is the ID of the API key of the request, if any.
+	KeyID string
+	// Scopes is granted by the API key, or by the roles of the token.
+	Scopes []string
+}
+
+// Can checks the identity is granted the scope.
+func (id *Identity) Can(scope string) bool {
+	if contains(id.Scopes, ScopeAdmin) || contains(id.Scopes, scope) {
+		return true
+	}
+	return scope == ScopeTasksRead && contains(id.Scopes, ScopeTasksWrite)
+}
+
+// Authenticator find the identity of the caller from the request
+// credentials.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// Authenticators try each authenticator until one knows the credentials.
+type Authenticators []Authenticator
+
+// Authenticate return the identity of the first authenticator knowing the
+// credentials.
+func (as Authenticators) Authenticate(r *http.Request) (*Identity, error) {
+	for _, a := range as {
+		id, err := a.Authenticate(r)
+		if err != ErrNoCredentials {
+			return id, err
+		}
+	}
+	return nil, ErrNoCredentials
+}
+
+// StaticKey authenticate a fixed admin key, it is used to mint the first API
+// keys.
+type StaticKey struct {
+	Key string
+}
+
+// Authenticate grant the admin scope to the static key, other credentials
+// are left to the next authenticators.
+func (sk *StaticKey) Authenticate(r *http.Request) (*Identity, error) {
+	token := credentials(r)
+	if sk.Key == "" || subtle.ConstantTimeCompare([]byte(token), []byte(sk.Key)) != 1 {
+		return nil, ErrNoCredentials
+	}
+	return &Identity{Scopes: []string{ScopeAdmin}}, nil
+}
+
+// credentials return the token of the Authorization bearer or the X-API-Key
+// header.
+func credentials(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// requiredScope return the scope of a request: admin for the /admin/ routes
+// and the changes of users, custom fields and tags, tasks:read for the safe
+// methods and tasks:write otherwise.
+func requiredScope(r *http.Request) string {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/admin/"):
+		return ScopeAdmin
+	case !safe && (strings.HasPrefix(r.URL.Path, "/users/") || strings.HasPrefix(r.URL.Path, "/fields/") || strings.HasPrefix(r.URL.Path, "/tags/")):
+		return ScopeAdmin
+	case safe:
+		return ScopeTasksRead
+	}
+	return ScopeTasksWrite
+}
+
+// RequireAuth reject the requests without valid credentials with a 401 and
+// the requests out of the scopes or the tenant of the caller with a 403. The
+// identity and the tenant of the caller are set in the request context. The
+// root path is public.
+func RequireAuth(auth Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := auth.Authenticate(r)
+		if err != nil {
+			w.Header().Set("Content-Type", jsonapi.MediaType)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="task"`)
+			if err != ErrNoCredentials && err != ErrInvalidCredentials {
+				writeError(w, http.StatusInternalServerError, "Authentication Error", err.Error())
+				return
+			}
+			writeError(w, http.StatusUnauthorized, "Authentication Error", err.Error())
+			return
+		}
+
+		if scope := requiredScope(r); !id.Can(scope) {
+			w.Header().Set("Content-Type", jsonapi.MediaType)
+			writeError(w, http.StatusForbidden, "Authorization Error", "the "+scope+" scope is required")
+			return
+		}
+
+		tenant, err := resolveTenant(r, id)
+		if err != nil {
+			w.Header().Set("Content-Type", jsonapi.MediaType)
+			writeError(w, http.StatusForbidden, "Authorization Error", err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, WithTenant(WithIdentity(r, id), tenant))
+	})
+}
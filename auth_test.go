@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIdentityCan(t *testing.T) {
+	id := &Identity{Scopes: []string{ScopeTasksWrite}}
+	if !id.Can(ScopeTasksRead) || !id.Can(ScopeTasksWrite) || id.Can(ScopeAdmin) {
+		t.Errorf("expected tasks:write to grant the task scopes only")
+	}
+
+	id = &Identity{Scopes: []string{ScopeTasksRead}}
+	if id.Can(ScopeTasksWrite) {
+		t.Errorf("expected tasks:read to not grant tasks:write")
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	h := RequireAuth(&StaticKey{Key: "secret"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		header string
+		code   int
+	}{
+		{http.MethodGet, "/", "", http.StatusOK},
+		{http.MethodGet, "/task/", "", http.StatusUnauthorized},
+		{http.MethodGet, "/task/", "Bearer wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/task/", "Bearer secret", http.StatusOK},
+		{http.MethodGet, "/admin/api-keys", "Bearer secret", http.StatusOK},
+	} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		h.ServeHTTP(rr, req)
+
+		// Test status code.
+		if rr.Code != tc.code {
+			t.Errorf("%v %v: expected %v, got %v", tc.method, tc.path, tc.code, rr.Code)
+		}
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		path   string
+		scope  string
+	}{
+		{http.MethodGet, "/task/", ScopeTasksRead},
+		{http.MethodPost, "/task/", ScopeTasksWrite},
+		{http.MethodPost, "/fields/", ScopeAdmin},
+		{http.MethodDelete, "/tags/5a0c4b8e1d41c82f5c3b2a10", ScopeAdmin},
+		{http.MethodGet, "/tags/", ScopeTasksRead},
+		{http.MethodGet, "/admin/api-keys", ScopeAdmin},
+	} {
+		req, _ := http.NewRequest(tc.method, tc.path, nil)
+		if s := requiredScope(req); s != tc.scope {
+			t.Errorf("%v %v: expected %v, got %v", tc.method, tc.path, tc.scope, s)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	mgo "gopkg.in/mgo.v2"
+)
+
+// Blob is the content of a stored blob.
+type Blob interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// BlobStore keep the content of the attachments by key.
+type BlobStore interface {
+	// Put store the content under the key.
+	Put(key string, r io.Reader) error
+	// Open return the content of the key.
+	Open(key string) (Blob, error)
+	// Delete remove the content of the key.
+	Delete(key string) error
+}
+
+// blobs is the blob store in use.
+var blobs BlobStore = &FileStore{Dir: "blobs"}
+
+// blobKey is the format of the keys, keys are used as file names.
+var blobKey = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// FileStore keep the blobs in a directory of the local filesystem.
+type FileStore struct {
+	Dir string
+}
+
+// path return the file of a key.
+func (fs *FileStore) path(key string) (string, error) {
+	if !blobKey.MatchString(key) {
+		return "", fmt.Errorf("invalid blob key %v", key)
+	}
+	return filepath.Join(fs.Dir, key), nil
+}
+
+// Put write the content to a temporary file then move it to the key file.
+func (fs *FileStore) Put(key string, r io.Reader) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(fs.Dir, 0750); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(fs.Dir, ".upload-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Open return the file of the key.
+func (fs *FileStore) Open(key string) (Blob, error) {
+	path, err := fs.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete remove the file of the key.
+func (fs *FileStore) Delete(key string) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GridFSStore keep the blobs in the mongoDB GridFS.
+type GridFSStore struct {
+	Prefix string
+}
+
+// gridFS open a connection to the GridFS.
+func (gs *GridFSStore) gridFS() (*mgo.Session, *mgo.GridFS, error) {
+	s, c, err := getCollection("", gs.Prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, c.Database.GridFS(gs.Prefix), nil
+}
+
+// Put write the content to a new GridFS file named by the key.
+func (gs *GridFSStore) Put(key string, r io.Reader) error {
+	s, fs, err := gs.gridFS()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	f, err := fs.Create(key)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Abort()
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// gridBlob close the session with the file.
+type gridBlob struct {
+	*mgo.GridFile
+	session *mgo.Session
+}
+
+// Close close the file and its session.
+func (b *gridBlob) Close() error {
+	defer b.session.Close()
+	return b.GridFile.Close()
+}
+
+// Open return the GridFS file of the key.
+func (gs *GridFSStore) Open(key string) (Blob, error) {
+	s, fs, err := gs.gridFS()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fs.Open(key)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &gridBlob{GridFile: f, session: s}, nil
+}
+
+// Delete remove the GridFS files of the key.
+func (gs *GridFSStore) Delete(key string) error {
+	s, fs, err := gs.gridFS()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := fs.Remove(key); err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+	return nil
+}
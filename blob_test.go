@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer os.RemoveAll(dir)
+	fs := &FileStore{Dir: dir}
+
+	if err := fs.Put("abc", strings.NewReader("some content")); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	b, err := fs.Open("abc")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	content, _ := ioutil.ReadAll(b)
+	b.Close()
+	if string(content) != "some content" {
+		t.Errorf("expected some content, got %v", string(content))
+	}
+
+	if err := fs.Delete("abc"); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if _, err := fs.Open("abc"); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestFileStoreInvalidKey(t *testing.T) {
+	fs := &FileStore{Dir: os.TempDir()}
+	if err := fs.Put("../escape", strings.NewReader("")); err == nil {
+		t.Errorf("expected an invalid key error")
+	}
+}
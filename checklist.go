@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrChecklistChanged is returned when reordering a checklist modified in
+// the meantime.
+var ErrChecklistChanged = fmt.Errorf("checklist have been modified")
+
+// ErrNothingToChange is returned when editing an item without text nor
+// checked.
+var ErrNothingToChange = fmt.Errorf("the text or checked attribute is required")
+
+// ErrInvalidOrder is returned when reordering a checklist without listing
+// each of its items once.
+var ErrInvalidOrder = fmt.Errorf("the order must list every checklist item once")
+
+// ChecklistItem is a step of a task checklist.
+type ChecklistItem struct {
+	ID   string `bson:"id" jsonapi:"primary,checklist-item"`
+	Text string `bson:"text" validate:"required,max=500" jsonapi:"attr,text"`
+	// Checked is nil when an edit leaves it unchanged.
+	Checked *bool `bson:"checked" jsonapi:"attr,checked"`
+}
+
+func init() {
+	resourceTypes["checklist-item"] = ChecklistItem{}
+}
+
+// Validate checks attributes's integrity.
+func (i *ChecklistItem) Validate() error {
+	err := validator.New().Struct(i)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// checklistProgress return the checked items of a checklist.
+func checklistProgress(items []*ChecklistItem) *Progress {
+	if len(items) == 0 {
+		return nil
+	}
+	p := &Progress{Total: len(items)}
+	for _, i := range items {
+		if i.Checked != nil && *i.Checked {
+			p.Done++
+		}
+	}
+	return p
+}
+
+// updateChecklist apply an update to the checklist of a task and return the
+// new checklist, a query missing the task returns mgo.ErrNotFound. The change
+// is recorded in the audit as done by the actor.
+func updateChecklist(tenant string, actor string, taskID string, query bson.M, update bson.M) ([]*ChecklistItem, error) {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(taskID) {
+		return nil, fmt.Errorf("id value is not valid (%v)", taskID)
+	}
+	query["_id"] = bson.ObjectIdHex(taskID)
+
+	if err := auditedUpdate(c, actor, query, update); err != nil {
+		return nil, err
+	}
+
+	t := &Task{}
+	if err := c.FindId(query["_id"]).Select(bson.M{"checklist": 1}).One(t); err != nil {
+		return nil, err
+	}
+	return t.Checklist, nil
+}
+
+// Checklist return the checklist of a task.
+func Checklist(tenant string, taskID string) ([]*ChecklistItem, error) {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(taskID) {
+		return nil, fmt.Errorf("id value is not valid (%v)", taskID)
+	}
+
+	t := &Task{}
+	if err := c.FindId(bson.ObjectIdHex(taskID)).Select(bson.M{"checklist": 1}).One(t); err != nil {
+		return nil, err
+	}
+	return t.Checklist, nil
+}
+
+// AddChecklistItem insert an item in the checklist at a position, a negative
+// position append the item. The checklist changes are recorded in the audit
+// as done by the actor.
+func AddChecklistItem(tenant string, actor string, taskID string, item *ChecklistItem, position int) ([]*ChecklistItem, error) {
+	item.ID = bson.NewObjectId().Hex()
+	if item.Checked == nil {
+		item.Checked = new(bool)
+	}
+	push := bson.M{"$each": []*ChecklistItem{item}}
+	if position >= 0 {
+		push["$position"] = position
+	}
+	return updateChecklist(tenant, actor, taskID, bson.M{}, bson.M{"$push": bson.M{"checklist": push}})
+}
+
+// EditChecklistItem change the text of an item when not empty, and check or
+// uncheck it when checked is set.
+func EditChecklistItem(tenant string, actor string, taskID string, item *ChecklistItem) ([]*ChecklistItem, error) {
+	set := bson.M{}
+	if item.Checked != nil {
+		set["checklist.$.checked"] = *item.Checked
+	}
+	if item.Text != "" {
+		set["checklist.$.text"] = item.Text
+	}
+	if len(set) == 0 {
+		return nil, ErrNothingToChange
+	}
+	return updateChecklist(tenant, actor, taskID, bson.M{"checklist.id": item.ID}, bson.M{"$set": set})
+}
+
+// RemoveChecklistItem remove an item of the checklist.
+func RemoveChecklistItem(tenant string, actor string, taskID string, itemID string) ([]*ChecklistItem, error) {
+	return updateChecklist(tenant, actor, taskID, bson.M{"checklist.id": itemID}, bson.M{"$pull": bson.M{"checklist": bson.M{"id": itemID}}})
+}
+
+// ReorderChecklist sort the checklist in the order of the item IDs, which
+// must list every item. The order is only applied if the checklist have not
+// changed since it was read.
+func ReorderChecklist(tenant string, actor string, taskID string, ids []string) ([]*ChecklistItem, error) {
+	items, err := Checklist(tenant, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != len(items) {
+		return nil, ErrInvalidOrder
+	}
+
+	byID := map[string]*ChecklistItem{}
+	for _, i := range items {
+		byID[i.ID] = i
+	}
+	sorted := make([]*ChecklistItem, 0, len(ids))
+	for _, id := range ids {
+		i, ok := byID[id]
+		if !ok {
+			return nil, ErrInvalidOrder
+		}
+		sorted = append(sorted, i)
+		delete(byID, id)
+	}
+
+	items, err = updateChecklist(tenant, actor, taskID, bson.M{"checklist": items}, bson.M{"$set": bson.M{"checklist": sorted}})
+	if err == mgo.ErrNotFound {
+		return nil, ErrChecklistChanged
+	}
+	return items, err
+}
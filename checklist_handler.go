@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+	mgo "gopkg.in/mgo.v2"
+)
+
+// writeChecklist write the checklist of a task or the error of its update.
+func writeChecklist(w http.ResponseWriter, items []*ChecklistItem, err error, status int) {
+	switch err {
+	case nil:
+	case mgo.ErrNotFound:
+		writeError(w, http.StatusNotFound, "Checklist Error", "task or checklist item not found")
+		return
+	case ErrChecklistChanged:
+		writeError(w, http.StatusConflict, "Checklist Error", err.Error())
+		return
+	case ErrNothingToChange, ErrInvalidOrder:
+		writeError(w, http.StatusBadRequest, "Checklist Error", err.Error())
+		return
+	default:
+		writeError(w, http.StatusInternalServerError, "Checklist Error", err.Error())
+		return
+	}
+
+	if items == nil {
+		items = []*ChecklistItem{}
+	}
+
+	// Set header status code.
+	w.WriteHeader(status)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, items, len(items))
+}
+
+// ReadChecklistAPI return the checklist of a task.
+func ReadChecklistAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleViewer, "Read Error"); err != nil {
+		return
+	}
+
+	items, err := Checklist(RequestTenant(r), mux.Vars(r)["sid"])
+	writeChecklist(w, items, err, http.StatusOK)
+}
+
+// AddChecklistAPI add an item to the checklist of a task, at the `position`
+// query parameter or at the end.
+func AddChecklistAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Update Error"); err != nil {
+		return
+	}
+
+	position := -1
+	if p := r.URL.Query().Get("position"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", "position must be a positive number")
+			return
+		}
+		position = n
+	}
+
+	item := new(ChecklistItem)
+	if err := populateModel(r.Body, w, item); err != nil {
+		return
+	}
+
+	if err := validateModel(item, w); err != nil {
+		return
+	}
+
+	items, err := AddChecklistItem(RequestTenant(r), RequestUser(r), mux.Vars(r)["sid"], item, position)
+	writeChecklist(w, items, err, http.StatusCreated)
+}
+
+// EditChecklistAPI check, uncheck or rename an item of the checklist.
+func EditChecklistAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Update Error"); err != nil {
+		return
+	}
+
+	item := new(ChecklistItem)
+	if err := populateModel(r.Body, w, item); err != nil {
+		return
+	}
+	item.ID = mux.Vars(r)["item"]
+
+	// The text is optional on edit, but limited like on creation.
+	if item.Text != "" {
+		if err := validateModel(item, w); err != nil {
+			return
+		}
+	}
+
+	items, err := EditChecklistItem(RequestTenant(r), RequestUser(r), mux.Vars(r)["sid"], item)
+	writeChecklist(w, items, err, http.StatusOK)
+}
+
+// ReorderChecklistAPI sort the checklist in the order of the items of the
+// payload.
+func ReorderChecklistAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Update Error"); err != nil {
+		return
+	}
+
+	data, err := jsonapi.UnmarshalManyPayload(r.Body, reflect.TypeOf(&ChecklistItem{}))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Json Unmarshal Payload Error", err.Error())
+		return
+	}
+
+	var ids []string
+	for _, row := range data {
+		ids = append(ids, row.(*ChecklistItem).ID)
+	}
+
+	items, err := ReorderChecklist(RequestTenant(r), RequestUser(r), mux.Vars(r)["sid"], ids)
+	writeChecklist(w, items, err, http.StatusOK)
+}
+
+// RemoveChecklistAPI remove an item of the checklist.
+func RemoveChecklistAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Update Error"); err != nil {
+		return
+	}
+
+	items, err := RemoveChecklistItem(RequestTenant(r), RequestUser(r), mux.Vars(r)["sid"], mux.Vars(r)["item"])
+	writeChecklist(w, items, err, http.StatusOK)
+}
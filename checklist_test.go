@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestChecklistItemWithEmptyText(t *testing.T) {
+	if err := (&ChecklistItem{}).Validate(); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestChecklistProgress(t *testing.T) {
+	if p := checklistProgress(nil); p != nil {
+		t.Errorf("expected no progress, got %v", p)
+	}
+
+	checked := true
+	p := checklistProgress([]*ChecklistItem{{Checked: &checked}, {}, {}})
+	if p.Done != 1 || p.Total != 3 {
+		t.Errorf("expected 1/3 items checked, got %v", p)
+	}
+}
+
+func TestChecklist(t *testing.T) {
+	task := createTaskOrFatal(t, "test checklist")
+
+	first, second := &ChecklistItem{Text: "first"}, &ChecklistItem{Text: "second"}
+	if _, err := AddChecklistItem("", "", task.SID, second, -1); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	items, err := AddChecklistItem("", "", task.SID, first, 0)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(items) != 2 || items[0].Text != "first" {
+		t.Errorf("expected first item on top, got %v", items)
+	}
+
+	// Check an item.
+	checked := true
+	items, err = EditChecklistItem("", "", task.SID, &ChecklistItem{ID: second.ID, Checked: &checked})
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if !*items[1].Checked || items[1].Text != "second" {
+		t.Errorf("expected second item checked, got %v", items[1])
+	}
+
+	// Rename an item without unchecking it.
+	items, err = EditChecklistItem("", "", task.SID, &ChecklistItem{ID: second.ID, Text: "second renamed"})
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if !*items[1].Checked || items[1].Text != "second renamed" {
+		t.Errorf("expected second item renamed and checked, got %v", items[1])
+	}
+
+	// Reorder the items, each one once.
+	if _, err := ReorderChecklist("", "", task.SID, []string{second.ID, second.ID}); err != ErrInvalidOrder {
+		t.Errorf("expected error %v, got %v", ErrInvalidOrder, err)
+	}
+	items, err = ReorderChecklist("", "", task.SID, []string{second.ID, first.ID})
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if items[0].ID != second.ID {
+		t.Errorf("expected second item on top, got %v", items)
+	}
+
+	// A task update keeps the checklist.
+	task.Title = "test checklist after update"
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	items, err = RemoveChecklistItem("", "", task.SID, first.ID)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(items) != 1 || items[0].ID != second.ID {
+		t.Errorf("expected only the second item, got %v", items)
+	}
+}
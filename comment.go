@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrNotAuthor is returned when a user change the comment of another user.
+var ErrNotAuthor = errors.New("only the author can change the comment")
+
+// ErrCommentChanged is returned when editing a comment modified in the
+// meantime.
+var ErrCommentChanged = errors.New("comment have been modified")
+
+// Comment is a Markdown message on a task.
+type Comment struct {
+	ID        bson.ObjectId `bson:"_id,omitempty"`
+	SID       string        `bson:"sid,omitempty" jsonapi:"primary,comment"`
+	TaskID    string        `bson:"task" jsonapi:"attr,task_id"`
+	Author    string        `bson:"author" jsonapi:"attr,author"`
+	Body      string        `bson:"body" validate:"required,max=10000" jsonapi:"attr,body"`
+	BodyHTML  string        `bson:"-" jsonapi:"attr,body_html,omitempty"`
+	CreatedAt time.Time     `bson:"createdAt" jsonapi:"attr,created_at"`
+	EditedAt  *time.Time    `bson:"editedAt,omitempty" jsonapi:"attr,edited_at,iso8601,omitempty"`
+	// History is the previous bodies, oldest first.
+	History []*CommentRevision `bson:"history,omitempty" jsonapi:"attr,history,omitempty"`
+	// Archived comments belong to deleted tasks.
+	Archived bool `bson:"archived"`
+}
+
+// CommentRevision is a previous body of an edited comment.
+type CommentRevision struct {
+	Body     string    `bson:"body" json:"body"`
+	EditedAt time.Time `bson:"editedAt" json:"edited_at"`
+}
+
+func init() {
+	resourceTypes["comment"] = Comment{}
+	taskRelations["comments"] = loadTaskComments
+}
+
+// Validate checks attributes's integrity.
+func (cm *Comment) Validate() error {
+	err := validator.New().Struct(cm)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// RenderBody fill the HTML rendering of the comment body.
+func (cm *Comment) RenderBody() {
+	cm.BodyHTML = renderMarkdown(cm.Body)
+}
+
+// Save persist a new comment on a task.
+func (cm *Comment) Save(tenant string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "comments")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	task, err := SelectTaskFields(tenant, cm.TaskID, []string{})
+	if err != nil {
+		return err
+	}
+	if !task.ID.Valid() {
+		return fmt.Errorf("unknown task %v", cm.TaskID)
+	}
+
+	cm.ID = bson.NewObjectId()
+	cm.SID = cm.ID.Hex()
+	cm.CreatedAt = time.Now()
+	cm.History = nil
+
+	if err := c.Insert(cm); err != nil {
+		return fmt.Errorf("can't to persist the comment (%v)", err)
+	}
+	return nil
+}
+
+// EditComment change the body of a comment of the user, the previous body is
+// kept in the history. An empty user is an admin and can edit every comment.
+func EditComment(tenant string, id string, user string, body string) (*Comment, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "comments")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	old, err := selectComment(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if user != "" && old.Author != user {
+		return nil, ErrNotAuthor
+	}
+
+	// Only apply the edit on the body which have been read.
+	now := time.Now()
+	cm := &Comment{}
+	_, err = c.Find(bson.M{"_id": old.ID, "body": old.Body}).Apply(mgo.Change{
+		Update: bson.M{
+			"$set":  bson.M{"body": body, "editedAt": now},
+			"$push": bson.M{"history": &CommentRevision{Body: old.Body, EditedAt: now}},
+		},
+		ReturnNew: true,
+	}, cm)
+	if err == mgo.ErrNotFound {
+		return nil, ErrCommentChanged
+	} else if err != nil {
+		return nil, fmt.Errorf("can't to edit the comment (%v)", err)
+	}
+	return cm, nil
+}
+
+// DeleteComment remove a comment of the user, an empty user is an admin and
+// can remove every comment.
+func DeleteComment(tenant string, id string, user string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "comments")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	cm, err := selectComment(c, id)
+	if err != nil {
+		return err
+	}
+	if user != "" && cm.Author != user {
+		return ErrNotAuthor
+	}
+	return c.RemoveId(cm.ID)
+}
+
+// selectComment find a comment by ID, archived comments and malformed IDs
+// are not found.
+func selectComment(c *mgo.Collection, id string) (*Comment, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, mgo.ErrNotFound
+	}
+	cm := &Comment{}
+	if err := c.Find(bson.M{"_id": bson.ObjectIdHex(id), "archived": false}).One(cm); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// SearchComments return a page of the comments of a task, oldest first, and
+// the total count.
+func SearchComments(tenant string, taskID string, page int, limit int) ([]*Comment, int, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "comments")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer s.Close()
+
+	q := c.Find(bson.M{"task": taskID, "archived": false})
+	n, err := q.Count()
+	if err != nil {
+		return nil, 0, fmt.Errorf("unexpected error %v", err)
+	}
+
+	comments := []*Comment{}
+	if err := q.Sort("createdAt", "_id").Skip((page - 1) * limit).Limit(limit).All(&comments); err != nil {
+		return nil, 0, fmt.Errorf("unexpected error %v", err)
+	}
+	return comments, n, nil
+}
+
+// archiveComments archive the comments of deleted tasks.
+func archiveComments(db *mgo.Database, taskIDs []string) error {
+	_, err := db.C("comments").UpdateAll(bson.M{"task": bson.M{"$in": taskIDs}}, bson.M{"$set": bson.M{"archived": true}})
+	return err
+}
+
+// loadTaskComments fill the comments relationship of the tasks.
+func loadTaskComments(tenant string, user string, tasks []*Task) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "comments")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var ids []string
+	for _, t := range tasks {
+		ids = append(ids, t.SID)
+	}
+
+	var comments []*Comment
+	if err := c.Find(bson.M{"task": bson.M{"$in": ids}, "archived": false}).Sort("createdAt", "_id").All(&comments); err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		t.Comments = []*Comment{}
+		for _, cm := range comments {
+			if cm.TaskID == t.SID {
+				t.Comments = append(t.Comments, cm)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+	mgo "gopkg.in/mgo.v2"
+)
+
+// writeCommentError write the error of a comment change.
+func writeCommentError(w http.ResponseWriter, title string, err error) {
+	switch err {
+	case ErrNotAuthor:
+		writeError(w, http.StatusForbidden, title, err.Error())
+	case mgo.ErrNotFound:
+		writeError(w, http.StatusNotFound, title, "comment not found")
+	case ErrCommentChanged:
+		writeError(w, http.StatusConflict, title, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, title, err.Error())
+	}
+}
+
+// CreateCommentAPI add a comment of the calling user on the task.
+func CreateCommentAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Save Error"); err != nil {
+		return
+	}
+
+	user := RequestUser(r)
+	if user == "" {
+		writeError(w, http.StatusUnauthorized, "Save Error", "a user is required to comment")
+		return
+	}
+
+	comment := new(Comment)
+	if err := populateModel(r.Body, w, comment); err != nil {
+		return
+	}
+	comment.TaskID = mux.Vars(r)["sid"]
+	comment.Author = user
+
+	if err := validateModel(comment, w); err != nil {
+		return
+	}
+
+	// Save the comment.
+	if err := comment.Save(RequestTenant(r)); err != nil {
+		writeError(w, http.StatusInternalServerError, "Save Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, comment)
+}
+
+// EditCommentAPI change the body of a comment of the calling user, the admins
+// can edit every comment.
+func EditCommentAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	comment := new(Comment)
+	if err := populateModel(r.Body, w, comment); err != nil {
+		return
+	}
+
+	if err := validateModel(comment, w); err != nil {
+		return
+	}
+
+	comment, err := EditComment(RequestTenant(r), mux.Vars(r)["sid"], accessUser(r), comment.Body)
+	if err != nil {
+		writeCommentError(w, "Update Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, comment)
+}
+
+// DeleteCommentAPI remove a comment of the calling user, or any comment for
+// the admins, and return a 204 (no-content) response.
+func DeleteCommentAPI(w http.ResponseWriter, r *http.Request) {
+
+	if err := DeleteComment(RequestTenant(r), mux.Vars(r)["sid"], accessUser(r)); err != nil {
+		writeCommentError(w, "Delete Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SearchCommentAPI return a page of the comments of a task.
+func SearchCommentAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	params, err := ParseParams(r, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleViewer, "Search Error"); err != nil {
+		return
+	}
+
+	// Defaults params.
+	page := 1
+	limit := 20
+
+	// Get all params.
+	v := r.URL.Query()
+
+	if p := v.Get("page"); p != "" {
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", "page must be a positive number")
+			return
+		}
+	}
+
+	if l := v.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", "limit must be a positive number")
+			return
+		}
+	}
+
+	comments, n, err := SearchComments(RequestTenant(r), mux.Vars(r)["sid"], page, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	if params.RenderHTML {
+		for _, c := range comments {
+			c.RenderBody()
+		}
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalManyPayload(w, comments, n)
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func createCommentOrFatal(t *testing.T, task *Task, author string, body string) *Comment {
+	cm := &Comment{TaskID: task.SID, Author: author, Body: body}
+	if err := cm.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return cm
+}
+
+func TestEditCommentHistory(t *testing.T) {
+	task := createTaskOrFatal(t, "test edit comment")
+	cm := createCommentOrFatal(t, task, "alice", "first body")
+
+	if _, err := EditComment("", cm.SID, "bob", "not mine"); err != ErrNotAuthor {
+		t.Errorf("expected error %v, got %v", ErrNotAuthor, err)
+	}
+
+	edited, err := EditComment("", cm.SID, "alice", "second body")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if edited.Body != "second body" || len(edited.History) != 1 || edited.History[0].Body != "first body" {
+		t.Errorf("expected the first body in history, got %v", edited.History)
+	}
+}
+
+func TestSearchCommentsPages(t *testing.T) {
+	task := createTaskOrFatal(t, "test search comments")
+	for i := 0; i < 3; i++ {
+		createCommentOrFatal(t, task, "alice", "a comment")
+	}
+
+	comments, n, err := SearchComments("", task.SID, 2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if n != 3 || len(comments) != 1 {
+		t.Errorf("expected 1 comment of 3 on page 2, got %v of %v", len(comments), n)
+	}
+}
+
+func TestDeleteTaskArchiveComments(t *testing.T) {
+	task := createTaskOrFatal(t, "test delete task with comments")
+	createCommentOrFatal(t, task, "alice", "an archived comment")
+
+	if err := DeleteTask("", task.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if _, n, _ := SearchComments("", task.SID, 1, 10); n != 0 {
+		t.Errorf("expected no comments, got %v", n)
+	}
+}
+
+func TestCreateCommentAPIWithoutUser(t *testing.T) {
+	task := createTaskOrFatal(t, "test create comment api without user")
+
+	m := mux.NewRouter()
+	m.HandleFunc("/task/{sid}/comments", CreateCommentAPI)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/task/"+task.SID+"/comments", strings.NewReader("{\"data\": {\"type\": \"comment\", \"attributes\": {\"body\": \"hello\"}}}"))
+
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDeleteCommentAPIAsAdmin(t *testing.T) {
+	task := createTaskOrFatal(t, "test delete comment api as admin")
+	cm := createCommentOrFatal(t, task, "alice", "moderated")
+
+	m := mux.NewRouter()
+	m.HandleFunc("/comments/{sid}", DeleteCommentAPI)
+
+	// A member can't remove the comment of another user.
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/comments/"+cm.SID, strings.NewReader(""))
+	m.ServeHTTP(rr, WithUser(req, "bob"))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, "/comments/"+cm.SID, strings.NewReader(""))
+	m.ServeHTTP(rr, WithIdentity(req, &Identity{UserID: "carol", Scopes: []string{ScopeAdmin}}))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDeleteCommentAPIInvalidID(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/comments/{sid}", DeleteCommentAPI)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/comments/unknown", strings.NewReader(""))
+	m.ServeHTTP(rr, WithUser(req, "alice"))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
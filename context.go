@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextKey is the type of the request context keys.
+type contextKey string
+
+// Context keys of the request.
+const (
+	identityKey = contextKey("identity")
+	tenantKey   = contextKey("tenant")
+)
+
+// WithIdentity return a copy of the request for a calling identity.
+func WithIdentity(r *http.Request, id *Identity) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), identityKey, id))
+}
+
+// WithUser return a copy of the request for a calling user with every task
+// scope.
+func WithUser(r *http.Request, userID string) *http.Request {
+	return WithIdentity(r, &Identity{UserID: userID, Scopes: []string{ScopeTasksWrite}})
+}
+
+// RequestIdentity return the identity of the caller, nil when the request
+// isn't authenticated.
+func RequestIdentity(r *http.Request) *Identity {
+	id, _ := r.Context().Value(identityKey).(*Identity)
+	return id
+}
+
+// RequestUser return the ID of the calling user.
+func RequestUser(r *http.Request) string {
+	if id := RequestIdentity(r); id != nil {
+		return id.UserID
+	}
+	return ""
+}
+
+// accessUser return the user the tasks are restricted to, the admins and the
+// requests without identity see every task.
+func accessUser(r *http.Request) string {
+	id := RequestIdentity(r)
+	if id == nil || id.Can(ScopeAdmin) {
+		return ""
+	}
+	return id.UserID
+}
+
+// WithTenant return a copy of the request for a tenant.
+func WithTenant(r *http.Request, tenant string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), tenantKey, tenant))
+}
+
+// RequestTenant return the tenant of the request, the default tenant when
+// none was resolved.
+func RequestTenant(r *http.Request) string {
+	tenant, _ := r.Context().Value(tenantKey).(string)
+	return tenant
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/jsonapi"
+)
+
+// CORS allow the browser clients of other origins to call the API.
+type CORS struct {
+	// Origins is the allowed origins, * matches any origin and
+	// https://*.example.com any subdomain.
+	Origins []string
+	Methods []string
+	// Headers is the request headers allowed, case insensitive.
+	Headers     []string
+	Credentials bool
+	// MaxAge is how long the browsers cache the preflights.
+	MaxAge time.Duration
+}
+
+// NewCORS create a CORS policy for the origins with the methods and headers
+// of the API.
+func NewCORS(origins []string) *CORS {
+	return &CORS{
+		Origins: origins,
+		Methods: []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPatch, http.MethodDelete},
+		Headers: []string{"Authorization", "Content-Type", "Accept", "Range", "X-API-Key", "X-Tenant"},
+		MaxAge:  10 * time.Minute,
+	}
+}
+
+// errCORSWildcard is returned for a policy allowing the credentials of any
+// origin, any site could then read the responses of the users.
+var errCORSWildcard = errors.New("the * origin can't be allowed with the credentials")
+
+// Validate checks the policy is safe.
+func (c *CORS) Validate() error {
+	if c.Credentials && contains(c.Origins, "*") {
+		return errCORSWildcard
+	}
+	return nil
+}
+
+// corsExposed is the response headers readable by the browser clients.
+var corsExposed = []string{"Location", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+
+// allowOrigin checks an origin matches one of the allowed origins, * never
+// matches when the credentials are allowed.
+func (c *CORS) allowOrigin(origin string) bool {
+	for _, o := range c.Origins {
+		if (o == "*" && !c.Credentials) || o == origin {
+			return true
+		}
+		if i := strings.Index(o, "*"); i >= 0 {
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) &&
+				!strings.Contains(origin[len(prefix):len(origin)-len(suffix)], "/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowHeaders checks every requested header is allowed.
+func (c *CORS) allowHeaders(requested string) bool {
+	for _, h := range splitList(requested) {
+		allowed := false
+		for _, a := range c.Headers {
+			if strings.EqualFold(h, a) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// setOrigin set the allowed origin of the response. With the credentials, only
+// the origins matched by name or subdomain are allowed and set.
+func (c *CORS) setOrigin(w http.ResponseWriter, origin string) {
+	w.Header().Add("Vary", "Origin")
+	if c.Credentials {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	} else if contains(c.Origins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.Credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Handler answer the preflights before the authentication and the routes, and
+// add the CORS headers to the requests of the allowed origins. The requests
+// without origin are left unchanged.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		method := r.Header.Get("Access-Control-Request-Method")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Simple and actual requests.
+		if r.Method != http.MethodOptions || method == "" {
+			if c.allowOrigin(origin) {
+				c.setOrigin(w, origin)
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposed, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Preflight requests.
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		requested := r.Header.Get("Access-Control-Request-Headers")
+		if !c.allowOrigin(origin) || !contains(c.Methods, method) || !c.allowHeaders(requested) {
+			w.Header().Set("Content-Type", jsonapi.MediaType)
+			writeError(w, http.StatusForbidden, "CORS Error", "the origin, method or headers are not allowed")
+			return
+		}
+		c.setOrigin(w, origin)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.Methods, ", "))
+		if requested != "" {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.Headers, ", "))
+		}
+		if c.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCORSAllowOrigin(t *testing.T) {
+	c := NewCORS([]string{"https://app.example.com", "https://*.example.org"})
+	for origin, expected := range map[string]bool{
+		"https://app.example.com":     true,
+		"https://evil.example.com":    false,
+		"https://staging.example.org": true,
+		"https://example.org":         false,
+		"http://staging.example.org":  false,
+	} {
+		if c.allowOrigin(origin) != expected {
+			t.Errorf("expected %v for %v", expected, origin)
+		}
+	}
+}
+
+func TestCORSWildcardCredentials(t *testing.T) {
+	c := NewCORS([]string{"*"})
+	if !c.allowOrigin("https://any.example.com") {
+		t.Errorf("expected * to allow any origin")
+	}
+
+	c.Credentials = true
+	if err := c.Validate(); err != errCORSWildcard {
+		t.Errorf("expected error %v, got %v", errCORSWildcard, err)
+	}
+	if c.allowOrigin("https://any.example.com") {
+		t.Errorf("expected * to allow no origin with the credentials")
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/task/", SearchTaskAPI).Methods(http.MethodGet)
+	c := NewCORS([]string{"https://app.example.com"})
+	c.Credentials = true
+	h := c.Handler(RequireAuth(&StaticKey{Key: "secret"}, m))
+
+	for _, tc := range []struct {
+		origin  string
+		method  string
+		headers string
+		code    int
+	}{
+		{"https://app.example.com", http.MethodPost, "Authorization, Content-Type", http.StatusNoContent},
+		{"https://app.example.com", http.MethodPatch, "x-tenant", http.StatusNoContent},
+		{"https://evil.example.com", http.MethodPost, "", http.StatusForbidden},
+		{"https://app.example.com", http.MethodPut, "", http.StatusForbidden},
+		{"https://app.example.com", http.MethodPost, "X-Custom", http.StatusForbidden},
+	} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodOptions, "/task/", nil)
+		req.Header.Set("Origin", tc.origin)
+		req.Header.Set("Access-Control-Request-Method", tc.method)
+		if tc.headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", tc.headers)
+		}
+		h.ServeHTTP(rr, req)
+
+		// Test status code.
+		if rr.Code != tc.code {
+			t.Errorf("%v %v: expected %v, got %v", tc.origin, tc.method, tc.code, rr.Code)
+		}
+		if tc.code == http.StatusNoContent && (rr.Header().Get("Access-Control-Allow-Origin") != tc.origin ||
+			rr.Header().Get("Access-Control-Allow-Credentials") != "true") {
+			t.Errorf("%v %v: unexpected headers %v", tc.origin, tc.method, rr.Header())
+		}
+	}
+
+	// The actual requests are still authenticated.
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/task/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized || rr.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("Code : %v, Headers : %v", rr.Code, rr.Header())
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Custom field types.
+const (
+	FieldText   = "text"
+	FieldNumber = "number"
+	FieldEnum   = "enum"
+	FieldDate   = "date"
+	FieldBool   = "bool"
+)
+
+// fieldName is the format of the custom field names, names are used as
+// mongoDB keys and sort fields.
+var fieldName = regexp.MustCompile("^[a-z][a-z0-9_]*$")
+
+// CustomField is the schema of a custom attribute of the tasks. The name and
+// the type can't change once the field is created.
+type CustomField struct {
+	ID      bson.ObjectId `bson:"_id,omitempty"`
+	SID     string        `bson:"sid,omitempty" jsonapi:"primary,field"`
+	Name    string        `bson:"name" validate:"required,max=50" jsonapi:"attr,name"`
+	Label   string        `bson:"label,omitempty" validate:"max=100" jsonapi:"attr,label"`
+	Type    string        `bson:"type" validate:"required,oneof=text number enum date bool" jsonapi:"attr,type"`
+	Options []string      `bson:"options,omitempty" validate:"dive,required,max=100" jsonapi:"attr,options,omitempty"`
+}
+
+// CustomFieldError is a custom value not matching its field.
+type CustomFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *CustomFieldError) Error() string {
+	return fmt.Sprintf("custom field %v %v", e.Field, e.Reason)
+}
+
+func init() {
+	resourceTypes["field"] = CustomField{}
+}
+
+// Validate checks attributes's integrity.
+func (f *CustomField) Validate() error {
+	err := validator.New().Struct(f)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	if !fieldName.MatchString(f.Name) {
+		return &CustomFieldError{Field: f.Name, Reason: "name must be lowercase letters, digits and underscores"}
+	}
+	if f.Type == FieldEnum && len(f.Options) == 0 {
+		return &CustomFieldError{Field: f.Name, Reason: "of type enum requires options"}
+	}
+	if f.Type != FieldEnum && len(f.Options) > 0 {
+		return &CustomFieldError{Field: f.Name, Reason: "only enum have options"}
+	}
+	return nil
+}
+
+// check return the stored form of a value of the field. JSON numbers are
+// float64, dates are YYYY-MM-DD strings to keep them sortable.
+func (f *CustomField) check(v interface{}) (interface{}, error) {
+	switch f.Type {
+	case FieldText:
+		if s, ok := v.(string); ok && len(s) <= 1000 {
+			return s, nil
+		}
+		return nil, &CustomFieldError{Field: f.Name, Reason: "must be a text of 1000 characters at most"}
+	case FieldNumber:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case int:
+			return float64(n), nil
+		}
+		return nil, &CustomFieldError{Field: f.Name, Reason: "must be a number"}
+	case FieldEnum:
+		if s, ok := v.(string); ok && contains(f.Options, s) {
+			return s, nil
+		}
+		return nil, &CustomFieldError{Field: f.Name, Reason: fmt.Sprintf("must be one of %v", f.Options)}
+	case FieldDate:
+		if s, ok := v.(string); ok {
+			if _, err := time.Parse("2006-01-02", s); err == nil {
+				return s, nil
+			}
+		}
+		return nil, &CustomFieldError{Field: f.Name, Reason: "must be a YYYY-MM-DD date"}
+	case FieldBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, &CustomFieldError{Field: f.Name, Reason: "must be a boolean"}
+	}
+	return nil, &CustomFieldError{Field: f.Name, Reason: "has an unknown type"}
+}
+
+// Parse read a value of the field from a query parameter.
+func (f *CustomField) Parse(s string) (interface{}, error) {
+	switch f.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, &CustomFieldError{Field: f.Name, Reason: "must be a number"}
+		}
+		return n, nil
+	case FieldBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, &CustomFieldError{Field: f.Name, Reason: "must be a boolean"}
+		}
+		return b, nil
+	}
+	return f.check(s)
+}
+
+// validateCustom checks the custom values against their fields and convert
+// them to their stored form.
+func validateCustom(tenant string, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	fields, err := CustomFields(tenant)
+	if err != nil {
+		return err
+	}
+
+	for name, v := range values {
+		f, ok := fields[name]
+		if !ok {
+			return &CustomFieldError{Field: name, Reason: "is not defined"}
+		}
+		// A null value remove the field of the task.
+		if v == nil {
+			delete(values, name)
+			continue
+		}
+		if values[name], err = f.check(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CustomFields return the custom fields by name.
+func CustomFields(tenant string) (map[string]*CustomField, error) {
+	fields, err := SearchCustomFields(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*CustomField{}
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	return byName, nil
+}
+
+// Save persist the custom field into the database.
+func (f *CustomField) Save(tenant string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "fields")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Field names are unique.
+	n, err := c.Find(bson.M{"name": f.Name}).Count()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("field already exists %v", f.Name)
+	}
+
+	f.ID = bson.NewObjectId()
+	f.SID = f.ID.Hex()
+
+	if err := c.Insert(f); err != nil {
+		return fmt.Errorf("can't to persist the field (%v)", err)
+	}
+	return nil
+}
+
+// Update change the label or the options of an existing field, the values
+// already stored on the tasks are kept.
+func (f *CustomField) Update(tenant string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "fields")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(f.SID) {
+		return fmt.Errorf("ID is required for update field")
+	}
+	f.ID = bson.ObjectIdHex(f.SID)
+
+	old := &CustomField{}
+	if err := c.FindId(f.ID).One(old); err != nil {
+		return err
+	}
+	if old.Name != f.Name || old.Type != f.Type {
+		return &CustomFieldError{Field: old.Name, Reason: "can't change its name or type"}
+	}
+
+	if err := c.UpdateId(f.ID, bson.M{"$set": bson.M{"label": f.Label, "options": f.Options}}); err != nil {
+		return fmt.Errorf("can't to persist the field (%v)", err)
+	}
+	return nil
+}
+
+// SelectCustomField find a custom field by ID.
+func SelectCustomField(tenant string, id string) (*CustomField, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "fields")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, fmt.Errorf("id value is not valid (%v)", id)
+	}
+
+	f := &CustomField{}
+	if err := c.FindId(bson.ObjectIdHex(id)).One(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// SearchCustomFields return all the custom fields sorted by name.
+func SearchCustomFields(tenant string) ([]*CustomField, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "fields")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	fields := []*CustomField{}
+	if err := c.Find(nil).Sort("name").All(&fields); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+	return fields, nil
+}
+
+// DeleteCustomField remove a custom field and its values from every task, the
+// tasks changes are recorded in the audit as done by the actor.
+func DeleteCustomField(tenant string, actor string, id string) error {
+	f, err := SelectCustomField(tenant, id)
+	if err != nil {
+		return err
+	}
+
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "fields")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Clear the tasks first, a failure leaves the field in place.
+	key := "custom." + f.Name
+	if err := auditedUpdateAll(c.Database.C("tasks"), actor, bson.M{key: bson.M{"$exists": true}}, bson.M{"$unset": bson.M{key: ""}}); err != nil {
+		return fmt.Errorf("can't to clear the tasks (%v)", err)
+	}
+	return c.RemoveId(f.ID)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+)
+
+// writeFieldError write the error of a custom field change.
+func writeFieldError(w http.ResponseWriter, title string, err error) {
+	if _, ok := err.(*CustomFieldError); ok {
+		writeError(w, http.StatusBadRequest, title, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, title, err.Error())
+}
+
+// CreateFieldAPI define a new custom field with jsonapi params.
+func CreateFieldAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	field := new(CustomField)
+	if err := populateModel(r.Body, w, field); err != nil {
+		return
+	}
+
+	if err := validateModel(field, w); err != nil {
+		return
+	}
+
+	// Save the field.
+	if err := field.Save(RequestTenant(r)); err != nil {
+		writeFieldError(w, "Save Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, field)
+}
+
+// UpdateFieldAPI change the label or the options of a custom field.
+func UpdateFieldAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	field := new(CustomField)
+	if err := populateModel(r.Body, w, field); err != nil {
+		return
+	}
+
+	if err := validateModel(field, w); err != nil {
+		return
+	}
+
+	// Update the field.
+	if err := field.Update(RequestTenant(r)); err != nil {
+		writeFieldError(w, "Update Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, field)
+}
+
+// ReadFieldAPI return a custom field.
+func ReadFieldAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	params, err := ParseParams(r, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	field, err := SelectCustomField(RequestTenant(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Read Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalOnePayload(w, field)
+	})
+}
+
+// SearchFieldAPI return all the custom fields.
+func SearchFieldAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	params, err := ParseParams(r, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	fields, err := SearchCustomFields(RequestTenant(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalManyPayload(w, fields, len(fields))
+	})
+}
+
+// DeleteFieldAPI remove a custom field from all the tasks and return a 204
+// (no-content) response.
+func DeleteFieldAPI(w http.ResponseWriter, r *http.Request) {
+
+	if err := DeleteCustomField(RequestTenant(r), RequestUser(r), mux.Vars(r)["sid"]); err != nil {
+		writeError(w, http.StatusInternalServerError, "Delete Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
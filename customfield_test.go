@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func createFieldOrFatal(t *testing.T, f *CustomField) *CustomField {
+	if err := f.Validate(); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := f.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return f
+}
+
+func TestCustomFieldCheck(t *testing.T) {
+	f := &CustomField{Name: "environment", Type: FieldEnum, Options: []string{"staging", "production"}}
+	if _, err := f.check("production"); err != nil {
+		t.Errorf("unexpected error : %v", err)
+	}
+	if _, err := f.check("dev"); err == nil {
+		t.Errorf("expected an error for a value out of the options")
+	}
+
+	f = &CustomField{Name: "points", Type: FieldNumber}
+	if v, err := f.check(3); err != nil || v != 3.0 {
+		t.Errorf("expected 3.0, got %v (%v)", v, err)
+	}
+	if _, err := f.check("3"); err == nil {
+		t.Errorf("expected an error for a text number")
+	}
+
+	f = &CustomField{Name: "release", Type: FieldDate}
+	if _, err := f.check("2020-02-30"); err == nil {
+		t.Errorf("expected an error for an invalid date")
+	}
+}
+
+func TestCustomFieldValidate(t *testing.T) {
+	for _, f := range []*CustomField{
+		{Name: "Customer", Type: FieldText},
+		{Name: "kind", Type: FieldEnum},
+		{Name: "points", Type: FieldNumber, Options: []string{"1"}},
+	} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("expected an error for %v", f.Name)
+		}
+	}
+}
+
+func TestSortCustomField(t *testing.T) {
+	sort, err := sortFields([]string{"-custom.points"})
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if sort[0] != "-custom.points" {
+		t.Errorf("expected -custom.points, got %v", sort[0])
+	}
+	if _, err := sortFields([]string{"custom.$where"}); err == nil {
+		t.Errorf("expected an error for an invalid field name")
+	}
+}
+
+func TestTaskCustomValues(t *testing.T) {
+	createFieldOrFatal(t, &CustomField{Name: "story_points", Type: FieldNumber})
+
+	task := newTaskOrFatal(t, "test custom values")
+	task.Custom = map[string]interface{}{"undefined_field": "x"}
+	if err := task.Save(""); err == nil {
+		t.Errorf("expected an error for an undefined field")
+	}
+
+	task.Custom = map[string]interface{}{"story_points": 5}
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	search := &TaskSearch{All: true, Page: 1, Limit: 10, Custom: map[string]interface{}{"story_points": 5.0}}
+	tasks, n, err := search.Find()
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if n != 1 || tasks[0].SID != task.SID {
+		t.Errorf("expected the task %v, got %v tasks", task.SID, n)
+	}
+}
+
+func TestSearchTaskAPIUnknownCustomField(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/task/", SearchTaskAPI)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/task/?custom[unknown_field]=1", nil)
+
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestUpdateTaskKeepCustomValues(t *testing.T) {
+	createFieldOrFatal(t, &CustomField{Name: "effort", Type: FieldNumber})
+
+	task := newTaskOrFatal(t, "test update task keep custom values")
+	task.Custom = map[string]interface{}{"effort": 3}
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	patchTaskOrFatal(t, task.SID, `{"title": "test update task keep custom values"}`)
+	if u := selectTaskOrFatal(t, task.SID); u.Custom["effort"] != 3.0 {
+		t.Errorf("expected the custom values to be kept, got %v", u.Custom)
+	}
+
+	// A null custom attribute clear the values.
+	patchTaskOrFatal(t, task.SID, `{"title": "test update task keep custom values", "custom": null}`)
+	if u := selectTaskOrFatal(t, task.SID); len(u.Custom) != 0 {
+		t.Errorf("expected no custom values, got %v", u.Custom)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrDependencyCycle is returned when a task would depend on itself.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// ErrActionableConflict is returned when the actionable filter is combined
+// with a filter on the closed tasks.
+var ErrActionableConflict = errors.New("actionable tasks can't be done or in a terminal status")
+
+func init() {
+	taskRelations["blocked_by"] = loadTaskBlockers
+}
+
+// resolveBlockers checks the blocking tasks exist and do not depend on the
+// task, then set the stored blocker IDs.
+func (t *Task) resolveBlockers(c *mgo.Collection) error {
+	if t.BlockedBy == nil {
+		return nil
+	}
+
+	ids := []string{}
+	for _, b := range t.BlockedBy {
+		if !bson.IsObjectIdHex(b.SID) {
+			return fmt.Errorf("blocking task id value is not valid (%v)", b.SID)
+		}
+		if b.SID == t.SID {
+			return ErrDependencyCycle
+		}
+		if !contains(ids, b.SID) {
+			ids = append(ids, b.SID)
+		}
+	}
+
+	n, err := c.Find(bson.M{"sid": bson.M{"$in": ids}}).Count()
+	if err != nil {
+		return err
+	}
+	if n != len(ids) {
+		return fmt.Errorf("unknown blocking tasks %v", ids)
+	}
+
+	// The task must not be reachable from its blockers.
+	if t.SID != "" {
+		graph, err := dependencyGraph(c, ids)
+		if err != nil {
+			return err
+		}
+		if _, ok := graph[t.SID]; ok {
+			return ErrDependencyCycle
+		}
+	}
+
+	t.BlockedByIDs = ids
+	return nil
+}
+
+// dependencyGraph return the blockers of every task reachable from the
+// given tasks.
+func dependencyGraph(c *mgo.Collection, ids []string) (map[string][]string, error) {
+	graph := map[string][]string{}
+	for len(ids) > 0 {
+		var tasks []Task
+		if err := c.Find(bson.M{"sid": bson.M{"$in": ids}}).Select(bson.M{"sid": 1, "blockedBy": 1}).All(&tasks); err != nil {
+			return nil, err
+		}
+
+		ids = nil
+		for _, t := range tasks {
+			graph[t.SID] = t.BlockedByIDs
+			for _, b := range t.BlockedByIDs {
+				if _, ok := graph[b]; !ok && !contains(ids, b) {
+					ids = append(ids, b)
+				}
+			}
+		}
+	}
+	return graph, nil
+}
+
+// openBlockers return the ID of the tasks blocking another task which are
+// not done yet, a cancelled task doesn't block anymore.
+func openBlockers(c *mgo.Collection, ids []string) ([]string, error) {
+	query := bson.M{"done": false, "status": bson.M{"$nin": workflow.Terminal}}
+	if ids != nil {
+		query["sid"] = bson.M{"$in": ids}
+	} else {
+		var blockers []string
+		if err := c.Find(bson.M{"blockedBy.0": bson.M{"$exists": true}}).Distinct("blockedBy", &blockers); err != nil {
+			return nil, err
+		}
+		query["sid"] = bson.M{"$in": blockers}
+	}
+
+	open := []string{}
+	if err := c.Find(query).Distinct("sid", &open); err != nil {
+		return nil, err
+	}
+	return open, nil
+}
+
+// actionableFilter restrict the query to the tasks not done nor cancelled and
+// without open blockers.
+func actionableFilter(c *mgo.Collection, bq bson.M) error {
+	// The actionable tasks are the open ones.
+	if done, ok := bq["done"]; ok && done != false {
+		return ErrActionableConflict
+	}
+	if status, ok := bq["status"].(string); ok && workflow.IsTerminal(status) {
+		return ErrActionableConflict
+	}
+
+	open, err := openBlockers(c, nil)
+	if err != nil {
+		return err
+	}
+	// The status filter of the search is kept.
+	and, _ := bq["$and"].([]bson.M)
+	bq["$and"] = append(and, bson.M{"status": bson.M{"$nin": workflow.Terminal}})
+	bq["done"] = false
+	bq["blockedBy"] = bson.M{"$nin": open}
+	return nil
+}
+
+// LoadBlocked set the blocked attribute of the tasks.
+func LoadBlocked(tenant string, tasks []*Task) error {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var ids []string
+	for _, t := range tasks {
+		ids = append(ids, t.BlockedByIDs...)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	open, err := openBlockers(c, ids)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		t.Blocked = false
+		for _, b := range t.BlockedByIDs {
+			if contains(open, b) {
+				t.Blocked = true
+			}
+		}
+	}
+	return nil
+}
+
+// DependencyOrder return the task and all the tasks it depends on visible to
+// the user, sorted so each task comes after its blockers.
+func DependencyOrder(tenant string, user string, id string) ([]*Task, error) {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, fmt.Errorf("id value is not valid (%v)", id)
+	}
+
+	graph, err := dependencyGraph(c, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := graph[id]; !ok {
+		return nil, mgo.ErrNotFound
+	}
+
+	// Depth first post-order, blockers are visited before the task.
+	var order []string
+	state := map[string]int{}
+	var visit func(string) error
+	visit = func(n string) error {
+		switch state[n] {
+		case 1:
+			return ErrDependencyCycle
+		case 2:
+			return nil
+		}
+		state[n] = 1
+		for _, b := range graph[n] {
+			if err := visit(b); err != nil {
+				return err
+			}
+		}
+		state[n] = 2
+		order = append(order, n)
+		return nil
+	}
+	if err := visit(id); err != nil {
+		return nil, err
+	}
+
+	visible, err := visibleTo(c.Database, bson.M{"sid": bson.M{"$in": order}}, user)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*Task
+	if err := c.Find(visible).All(&tasks); err != nil {
+		return nil, err
+	}
+	byID := map[string]*Task{}
+	for _, t := range tasks {
+		t.setComputed()
+		byID[t.SID] = t
+	}
+
+	sorted := make([]*Task, 0, len(order))
+	for _, n := range order {
+		if t, ok := byID[n]; ok {
+			sorted = append(sorted, t)
+		}
+	}
+	return sorted, LoadBlocked(tenant, sorted)
+}
+
+// loadTaskBlockers fill the blocked_by relationship of the tasks with the
+// blockers visible to the user.
+func loadTaskBlockers(tenant string, user string, tasks []*Task) error {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var ids []string
+	for _, t := range tasks {
+		ids = append(ids, t.BlockedByIDs...)
+	}
+
+	visible, err := visibleTo(c.Database, bson.M{"sid": bson.M{"$in": ids}}, user)
+	if err != nil {
+		return err
+	}
+	var blockers []*Task
+	if err := c.Find(visible).All(&blockers); err != nil {
+		return err
+	}
+	byID := map[string]*Task{}
+	for _, b := range blockers {
+		b.setComputed()
+		byID[b.SID] = b
+	}
+
+	for _, t := range tasks {
+		t.BlockedBy = []*Task{}
+		for _, id := range t.BlockedByIDs {
+			if b, ok := byID[id]; ok {
+				t.BlockedBy = append(t.BlockedBy, b)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func createBlockedTaskOrFatal(t *testing.T, title string, blockers ...*Task) *Task {
+	task := newTaskOrFatal(t, title)
+	task.BlockedBy = blockers
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return task
+}
+
+func TestCancelledBlocker(t *testing.T) {
+	blocker := createTaskOrFatal(t, "test cancelled blocker")
+	task := createBlockedTaskOrFatal(t, "test cancelled blocker dependent", blocker)
+
+	blocker.Status = "cancelled"
+	if err := blocker.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := LoadBlocked("", []*Task{task}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if task.Blocked {
+		t.Errorf("expected a cancelled blocker to not block, got %v", task.Blocked)
+	}
+
+	s := &TaskSearch{Query: "test cancelled blocker", All: true, Actionable: true, Page: 1, Limit: 10}
+	tasks, _, err := s.Find()
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].SID != task.SID {
+		t.Errorf("expected only the dependent to be actionable, got %v", tasks)
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	a := createTaskOrFatal(t, "test dependency cycle a")
+	b := createBlockedTaskOrFatal(t, "test dependency cycle b", a)
+	c := createBlockedTaskOrFatal(t, "test dependency cycle c", b)
+
+	a.BlockedBy = []*Task{c}
+	if err := a.Update(""); err != ErrDependencyCycle {
+		t.Errorf("expected error %v, got %v", ErrDependencyCycle, err)
+	}
+}
+
+func TestBlockedTask(t *testing.T) {
+	blocker := createTaskOrFatal(t, "test blocked task blocker")
+	task := createBlockedTaskOrFatal(t, "test blocked task", blocker)
+
+	if err := LoadBlocked("", []*Task{task}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if !task.Blocked {
+		t.Errorf("expected a blocked task, got %v", task.Blocked)
+	}
+
+	s := &TaskSearch{Query: "test blocked task", All: true, Actionable: true, Page: 1, Limit: 10}
+	tasks, _, err := s.Find()
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].SID != blocker.SID {
+		t.Errorf("expected only the blocker to be actionable, got %v", tasks)
+	}
+
+	blocker.Status = "done"
+	if err := blocker.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := LoadBlocked("", []*Task{task}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if task.Blocked {
+		t.Errorf("expected an unblocked task, got %v", task.Blocked)
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	a := createTaskOrFatal(t, "test dependency order a")
+	b := createBlockedTaskOrFatal(t, "test dependency order b", a)
+	c := createBlockedTaskOrFatal(t, "test dependency order c", a, b)
+
+	tasks, err := DependencyOrder("", "", c.SID)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(tasks) != 3 || tasks[0].SID != a.SID || tasks[1].SID != b.SID || tasks[2].SID != c.SID {
+		t.Errorf("expected the order a, b, c, got %v", tasks)
+	}
+}
+
+func TestActionableFilterConflict(t *testing.T) {
+	if err := actionableFilter(nil, bson.M{"done": true}); err != ErrActionableConflict {
+		t.Errorf("expected %v, got %v", ErrActionableConflict, err)
+	}
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/task/?actionable=true&done=true", strings.NewReader(""))
+	http.HandlerFunc(SearchTaskAPI).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
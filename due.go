@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// maxDueYears is how far in the future a due date can be set.
+const maxDueYears = 100
+
+// ErrOverdueConflict is returned when the overdue filter is combined with a
+// filter on the closed tasks.
+var ErrOverdueConflict = errors.New("overdue tasks can't be done or in a terminal status")
+
+// dueFilters is the accepted values of the due filter.
+var dueFilters = []string{"overdue", "today", "week"}
+
+// parseDueTime read a RFC 3339 date with its time zone offset.
+func parseDueTime(s string) (time.Time, error) {
+	d, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("due date must be a RFC 3339 date (%v)", err)
+	}
+	return d, nil
+}
+
+// validateDueDate reject a due date which can't be parsed or which is not
+// within a sensible range.
+func validateDueDate(fl validator.FieldLevel) bool {
+	d, err := parseDueTime(fl.Field().String())
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+	return d.Year() >= 1970 && d.Before(now.AddDate(maxDueYears, 0, 0))
+}
+
+// parseDueDate set the due time from the due_at attribute.
+func (t *Task) parseDueDate() error {
+	if t.DueDate == "" {
+		return nil
+	}
+	d, err := parseDueTime(t.DueDate)
+	if err != nil {
+		return err
+	}
+	d = d.UTC()
+	t.DueAt = &d
+	return nil
+}
+
+// startOfDay return midnight of the day in the location.
+func startOfDay(now time.Time, loc *time.Location) time.Time {
+	now = now.In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+}
+
+// dueFilter add the due date condition to the mongoDB query.
+func dueFilter(bq bson.M, due string, loc *time.Location) error {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := time.Now()
+	day := startOfDay(now, loc)
+
+	switch due {
+	case "overdue":
+		// The overdue tasks are the open ones.
+		if done, ok := bq["done"]; ok && done != false {
+			return ErrOverdueConflict
+		}
+		if status, ok := bq["status"]; !ok {
+			bq["status"] = bson.M{"$nin": workflow.Terminal}
+		} else if s, _ := status.(string); workflow.IsTerminal(s) {
+			return ErrOverdueConflict
+		}
+		bq["dueAt"] = bson.M{"$lt": now}
+		bq["done"] = false
+	case "today":
+		bq["dueAt"] = bson.M{"$gte": day, "$lt": day.AddDate(0, 0, 1)}
+	case "week":
+		// Weeks start on monday.
+		monday := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		bq["dueAt"] = bson.M{"$gte": monday, "$lt": monday.AddDate(0, 0, 7)}
+	default:
+		return fmt.Errorf("unknown due filter %v", due)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestValidateDueDate(t *testing.T) {
+	task := newTaskOrFatal(t, "test due date validation")
+
+	task.DueDate = "2030-02-01T10:00:00+02:00"
+	if err := task.Validate(); err != nil {
+		t.Errorf("unexpected error (%v)", err)
+	}
+
+	for _, d := range []string{"tomorrow", "2030-02-01", "1900-01-01T00:00:00Z", "9999-01-01T00:00:00Z"} {
+		task.DueDate = d
+		if err := task.Validate(); err == nil {
+			t.Errorf("expected an error for due date '%v', got %v", d, err)
+		}
+	}
+}
+
+func TestParseDueDateWithOffset(t *testing.T) {
+	task := newTaskOrFatal(t, "test due date offset")
+	task.DueDate = "2030-02-01T10:00:00+02:00"
+	if err := task.parseDueDate(); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	expected := time.Date(2030, 2, 1, 8, 0, 0, 0, time.UTC)
+	if !task.DueAt.Equal(expected) {
+		t.Errorf("expected due time %v, got %v", expected, task.DueAt)
+	}
+}
+
+func TestDueFilter(t *testing.T) {
+	bq := bson.M{}
+	if err := dueFilter(bq, "week", time.UTC); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	r := bq["dueAt"].(bson.M)
+	if r["$gte"].(time.Time).Weekday() != time.Monday {
+		t.Errorf("expected a week starting on monday, got %v", r["$gte"])
+	}
+
+	if err := dueFilter(bson.M{}, "someday", time.UTC); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestOverdueTask(t *testing.T) {
+	task := newTaskOrFatal(t, "test overdue task")
+	task.DueDate = time.Now().Add(-time.Hour).Format(time.RFC3339)
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if !task.Overdue {
+		t.Errorf("expected an overdue task, got %v", task.Overdue)
+	}
+
+	tasks, _, err := (&TaskSearch{Query: "test overdue task", All: true, Due: "overdue", Page: 1, Limit: 10}).Find()
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if len(tasks) != 1 || !tasks[0].Overdue {
+		t.Errorf("expected 1 overdue task, got %v", len(tasks))
+	}
+}
+
+func TestOverdueFilterConflict(t *testing.T) {
+	if err := dueFilter(bson.M{"done": true}, "overdue", time.UTC); err != ErrOverdueConflict {
+		t.Errorf("expected %v, got %v", ErrOverdueConflict, err)
+	}
+	if err := dueFilter(bson.M{"status": "cancelled"}, "overdue", time.UTC); err != ErrOverdueConflict {
+		t.Errorf("expected %v, got %v", ErrOverdueConflict, err)
+	}
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/task/?due=overdue&done=true", strings.NewReader(""))
+	http.HandlerFunc(SearchTaskAPI).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestCancelledTaskNotOverdue(t *testing.T) {
+	task := newTaskOrFatal(t, "test cancelled task not overdue")
+	task.DueDate = time.Now().Add(-time.Hour).Format(time.RFC3339)
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	task.Status = "cancelled"
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if task.Overdue {
+		t.Errorf("expected a cancelled task not overdue, got %v", task.Overdue)
+	}
+
+	tasks, _, err := (&TaskSearch{Query: "test cancelled task not overdue", All: true, Due: "overdue", Page: 1, Limit: 10}).Find()
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("expected no overdue task, got %v", len(tasks))
+	}
+}
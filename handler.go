@@ -5,21 +5,70 @@ import (
 
 	validator "gopkg.in/go-playground/validator.v9"
 
+	"encoding/json"
+
 	"fmt"
 
 	"io"
 
+	"io/ioutil"
+
+	"bytes"
+
 	"strconv"
 
+	"strings"
+
+	"time"
+
 	"github.com/google/jsonapi"
 	"github.com/gorilla/mux"
+	mgo "gopkg.in/mgo.v2"
 )
 
 // populateTask create a task object with json properties.
 func populateTask(body io.ReadCloser, w http.ResponseWriter) (*Task, error) {
 
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Json Unmarshal Payload Error", err.Error())
+		return nil, err
+	}
+
 	task := new(Task)
-	if err := jsonapi.UnmarshalPayload(body, task); err != nil {
+	if err := populateModel(ioutil.NopCloser(bytes.NewReader(b)), w, task); err != nil {
+		return nil, err
+	}
+	task.fields = payloadFields(b)
+	return task, nil
+}
+
+// payloadFields return the attributes and relationships of a JSON:API
+// payload, the ones set to null included since the unmarshal skips them.
+func payloadFields(b []byte) map[string]bool {
+	var payload struct {
+		Data struct {
+			Attributes    map[string]json.RawMessage `json:"attributes"`
+			Relationships map[string]json.RawMessage `json:"relationships"`
+		} `json:"data"`
+	}
+	fields := map[string]bool{}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return fields
+	}
+	for k := range payload.Data.Attributes {
+		fields[k] = true
+	}
+	for k := range payload.Data.Relationships {
+		fields[k] = true
+	}
+	return fields
+}
+
+// populateModel fill a model with json properties.
+func populateModel(body io.ReadCloser, w http.ResponseWriter, model interface{}) error {
+
+	if err := jsonapi.UnmarshalPayload(body, model); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		if err := jsonapi.MarshalErrors(w, []*jsonapi.ErrorObject{{
 			Title:  "Json Unmarshal Payload Error",
@@ -28,34 +77,68 @@ func populateTask(body io.ReadCloser, w http.ResponseWriter) (*Task, error) {
 		}}); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
-		return nil, err
+		return err
 	}
-	return task, nil
+	return nil
 }
 
-// validateTask check the values's task.
-func validateTask(task *Task, w http.ResponseWriter) error {
+// validateTask check the values's task and its custom fields in the
+// tenant.
+func validateTask(tenant string, task *Task, w http.ResponseWriter) error {
+	if err := validateModel(task, w); err != nil {
+		return err
+	}
+	if err := validateCustom(tenant, task.Custom); err != nil {
+		writeValidationError(w, err)
+		return err
+	}
+	return nil
+}
 
-	if err := task.Validate(); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		var eos []*jsonapi.ErrorObject
-		for _, err := range err.(validator.ValidationErrors) {
-			eos = append(eos, &jsonapi.ErrorObject{
-				Title:  "Validation Error",
-				Detail: fmt.Sprintf("%s", err),
-				Status: "400",
-				Meta:   &map[string]interface{}{"field": err.Field(), "error": err.Tag(), "expected": err.Type(), "received": err.Value()},
-			})
-		}
+// validateModel check the values's model.
+func validateModel(model interface{ Validate() error }, w http.ResponseWriter) error {
 
-		if err := jsonapi.MarshalErrors(w, eos); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	if err := model.Validate(); err != nil {
+		writeValidationError(w, err)
 		return err
 	}
 	return nil
 }
 
+// writeValidationError write the errors of a validation.
+func writeValidationError(w http.ResponseWriter, err error) {
+	if ce, ok := err.(*CustomFieldError); ok {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonapi.MarshalErrors(w, []*jsonapi.ErrorObject{{
+			Title:  "Validation Error",
+			Detail: ce.Error(),
+			Status: "400",
+			Meta:   &map[string]interface{}{"field": "custom." + ce.Field},
+		}})
+		return
+	}
+	verrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Validation Error", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusBadRequest)
+	var eos []*jsonapi.ErrorObject
+	for _, err := range verrs {
+		eos = append(eos, &jsonapi.ErrorObject{
+			Title:  "Validation Error",
+			Detail: fmt.Sprintf("%s", err),
+			Status: "400",
+			Meta:   &map[string]interface{}{"field": err.Field(), "error": err.Tag(), "expected": err.Type(), "received": err.Value()},
+		})
+	}
+
+	if err := jsonapi.MarshalErrors(w, eos); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 // CreateTaskAPI create a new task with jsonapi params.
 func CreateTaskAPI(w http.ResponseWriter, r *http.Request) {
 	// Set the header content-type.
@@ -66,17 +149,44 @@ func CreateTaskAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := validateTask(task, w); err != nil {
+	// Create the task in the list or under the task of the nested route.
+	if list := mux.Vars(r)["list"]; list != "" {
+		task.List = &List{SID: list}
+	}
+	if parent := mux.Vars(r)["parent"]; parent != "" {
+		task.Parent = &Task{SID: parent}
+	}
+
+	if err := validateTask(RequestTenant(r), task, w); err != nil {
 		return
 	}
+	task.CreatedByID = RequestUser(r)
+
+	// Adding a task to a list or a parent needs to edit it.
+	if task.List != nil && task.List.SID != "" {
+		if err := authorizeList(w, r, task.List.SID, RoleEditor, "Save Error"); err != nil {
+			return
+		}
+	}
+	if task.Parent != nil && task.Parent.SID != "" {
+		if err := authorizeTask(w, r, task.Parent.SID, RoleEditor, "Save Error"); err != nil {
+			return
+		}
+	}
+	for _, b := range task.BlockedBy {
+		if err := authorizeTask(w, r, b.SID, RoleViewer, "Save Error"); err != nil {
+			return
+		}
+	}
 
 	// Save the task.
-	if err := task.Save(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if err := task.Save(RequestTenant(r)); err != nil {
+		status := taskErrorStatus(err)
+		w.WriteHeader(status)
 		if err := jsonapi.MarshalErrors(w, []*jsonapi.ErrorObject{{
 			Title:  "Save Error",
 			Detail: err.Error(),
-			Status: "500",
+			Status: strconv.Itoa(status),
 		}}); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
@@ -92,6 +202,20 @@ func CreateTaskAPI(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// taskErrorStatus return the status code of an error saving a task, the
+// unexpected errors are internal.
+func taskErrorStatus(err error) int {
+	switch err {
+	case ErrQuotaExceeded:
+		return http.StatusForbidden
+	case ErrDependencyCycle, ErrSubtaskCycle:
+		return http.StatusBadRequest
+	case ErrTitleTaken, ErrInvalidTransition, ErrOpenSubtasks:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 // UpdateTaskAPI bring up to date a specific Task.
 func UpdateTaskAPI(w http.ResponseWriter, r *http.Request) {
 
@@ -103,17 +227,22 @@ func UpdateTaskAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := validateTask(task, w); err != nil {
+	if err := validateTask(RequestTenant(r), task, w); err != nil {
 		return
 	}
 
 	// Update the task.
-	if err := task.Update(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	task.UpdatedByID = RequestUser(r)
+	if err := task.UpdateAs(RequestTenant(r), accessUser(r)); err == mgo.ErrNotFound || err == ErrForbidden {
+		writeAccessError(w, "Update Error", err)
+		return
+	} else if err != nil {
+		status := taskErrorStatus(err)
+		w.WriteHeader(status)
 		if err := jsonapi.MarshalErrors(w, []*jsonapi.ErrorObject{{
 			Title:  "Update Error",
 			Detail: err.Error(),
-			Status: "500",
+			Status: strconv.Itoa(status),
 		}}); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
@@ -130,9 +259,9 @@ func UpdateTaskAPI(w http.ResponseWriter, r *http.Request) {
 
 // DeleteTaskAPI remove a task and return a 204 (no-content) response
 func DeleteTaskAPI(w http.ResponseWriter, r *http.Request) {
-	
+
 	vars := mux.Vars(r)
-	
+
 	sid := vars["sid"]
 	if sid == "" {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -146,7 +275,13 @@ func DeleteTaskAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := DeleteTask(sid); err != nil {
+	// Subtasks are moved under the parent unless deleted with the task.
+	recursive := r.URL.Query().Get("subtasks") == "delete"
+	if err := DeleteTaskTreeAs(RequestTenant(r), accessUser(r), RequestUser(r), sid, recursive); err == mgo.ErrNotFound || err == ErrForbidden {
+		w.Header().Set("Content-Type", jsonapi.MediaType)
+		writeAccessError(w, "Delete Error", err)
+		return
+	} else if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		if err := jsonapi.MarshalErrors(w, []*jsonapi.ErrorObject{{
 			Title:  "Delete Error",
@@ -162,20 +297,117 @@ func DeleteTaskAPI(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// writeError write a JSON:API error document with the status code.
+func writeError(w http.ResponseWriter, status int, title string, detail string) {
+	w.WriteHeader(status)
+	if err := jsonapi.MarshalErrors(w, []*jsonapi.ErrorObject{{
+		Title:  title,
+		Detail: detail,
+		Status: strconv.Itoa(status),
+	}}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// userFilter return the user ID of a filter parameter, me is the calling
+// user.
+func userFilter(value string, user string) (string, error) {
+	if value != "me" {
+		return value, nil
+	}
+	if user == "" {
+		return "", fmt.Errorf("me requires an authenticated user")
+	}
+	return user, nil
+}
+
+// writeAccessError write a not found error for the resources the user can't
+// see and a forbidden error when its role is not enough.
+func writeAccessError(w http.ResponseWriter, title string, err error) {
+	switch err {
+	case mgo.ErrNotFound:
+		writeError(w, http.StatusNotFound, title, "not found")
+	case ErrForbidden:
+		writeError(w, http.StatusForbidden, title, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, title, err.Error())
+	}
+}
+
+// authorizeTask checks the calling user has the role on the task and write
+// the error otherwise.
+func authorizeTask(w http.ResponseWriter, r *http.Request, id string, required string, title string) error {
+	role, err := TaskRole(RequestTenant(r), accessUser(r), id)
+	if err == nil && role == "" {
+		err = mgo.ErrNotFound
+	} else if err == nil && !grants(role, required) {
+		err = ErrForbidden
+	}
+	if err != nil {
+		writeAccessError(w, title, err)
+	}
+	return err
+}
+
+// authorizeList checks the calling user has the role on the list and write
+// the error otherwise.
+func authorizeList(w http.ResponseWriter, r *http.Request, id string, required string, title string) error {
+	role, err := ListRole(RequestTenant(r), accessUser(r), id)
+	if err == nil && role == "" {
+		err = mgo.ErrNotFound
+	} else if err == nil && !grants(role, required) {
+		err = ErrForbidden
+	}
+	if err != nil {
+		writeAccessError(w, title, err)
+	}
+	return err
+}
+
 // ReadTaskAPI return a response with tasks encoding to json
 func ReadTaskAPI(w http.ResponseWriter, r *http.Request) {
 
 	// Set the header defaults.
 	w.Header().Set("Content-Type", jsonapi.MediaType)
-	w.WriteHeader(http.StatusOK)
+
+	params, err := ParseParams(r, TaskRelations())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
 
 	task := &Task{}
 	vars := mux.Vars(r)
 	if vars["query"] != "" {
-		task, _ = SelectTask(vars["query"])
+		if task, err = SelectTaskAs(RequestTenant(r), accessUser(r), vars["query"], params.Projected("task")); err != nil {
+			writeError(w, http.StatusInternalServerError, "Read Error", err.Error())
+			return
+		}
 	}
 
-	jsonapi.MarshalOnePayload(w, task)
+	if err := LoadTaskRelations(RequestTenant(r), accessUser(r), []*Task{task}, params.Include); err != nil {
+		writeError(w, http.StatusInternalServerError, "Read Error", err.Error())
+		return
+	}
+
+	if err := LoadSubtaskProgress(RequestTenant(r), accessUser(r), []*Task{task}); err != nil {
+		writeError(w, http.StatusInternalServerError, "Read Error", err.Error())
+		return
+	}
+
+	if err := LoadBlocked(RequestTenant(r), []*Task{task}); err != nil {
+		writeError(w, http.StatusInternalServerError, "Read Error", err.Error())
+		return
+	}
+
+	if params.RenderHTML {
+		task.RenderDescription()
+	}
+
+	w.WriteHeader(http.StatusOK)
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalOnePayload(w, task)
+	})
 }
 
 // SearchTaskAPI return a response with tasks encoding to json
@@ -183,18 +415,23 @@ func SearchTaskAPI(w http.ResponseWriter, r *http.Request) {
 
 	// Set the header defaults.
 	w.Header().Set("Content-Type", jsonapi.MediaType)
-	w.WriteHeader(http.StatusOK)
 
 	// Defaults params.
 	var err error
-	page := 1
-	limit := 10
+	search := &TaskSearch{Tenant: RequestTenant(r), Page: 1, Limit: 10, All: true}
 
 	// Get all params.
 	v := r.URL.Query()
 
+	params, err := ParseParams(r, TaskRelations())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+	search.Fields = params.Projected("task")
+
 	if p := v.Get("page"); p != "" {
-		page, err = strconv.Atoi(p)
+		search.Page, err = strconv.Atoi(p)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -202,33 +439,257 @@ func SearchTaskAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if l := v.Get("limit"); l != "" {
-		limit, err = strconv.Atoi(l)
+		search.Limit, err = strconv.Atoi(l)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 
-	// Query task.
-	var tasks []*Task
-	var n int
-	q := v.Get("query")
-	d := v.Get("done")
-
-	if d != "" {
-		bd, _ := strconv.ParseBool(d)
-		tasks, n, err = SearchTask(q, bd, false, page, limit)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	if status := v.Get("status"); status != "" {
+		if !contains(workflow.Statuses, status) {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", fmt.Sprintf("unknown status %v", status))
+			return
+		}
+		search.Status = status
+	}
+
+	// The nested route have the priority over the list parameter.
+	search.ListID = v.Get("list")
+	if list := mux.Vars(r)["list"]; list != "" {
+		search.ListID = list
+	}
+	search.ParentID = mux.Vars(r)["parent"]
+	search.Actionable, _ = strconv.ParseBool(v.Get("actionable"))
+
+	if tags := splitList(v.Get("tags")); tags != nil {
+		search.Tags = tags
+		switch m := v.Get("tags_match"); m {
+		case "", "any":
+		case "all":
+			search.AllTags = true
+		default:
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", fmt.Sprintf("unknown tags match %v", m))
+			return
+		}
+	}
+
+	if due := v.Get("due"); due != "" {
+		if !contains(dueFilters, due) {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", fmt.Sprintf("unknown due filter %v", due))
 			return
 		}
-	} else {
-		tasks, n, err = SearchTask(q, false, true, page, limit)
+		search.Due = due
+	}
+
+	if tz := v.Get("tz"); tz != "" {
+		search.Location, err = time.LoadLocation(tz)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+			return
+		}
+	}
+
+	search.User = accessUser(r)
+	if search.Assignee, err = userFilter(v.Get("assignee"), RequestUser(r)); err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+	if search.CreatedBy, err = userFilter(v.Get("created_by"), RequestUser(r)); err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	var fields map[string]*CustomField
+	for k := range v {
+		if !strings.HasPrefix(k, "custom[") || !strings.HasSuffix(k, "]") {
+			continue
+		}
+		if search.Custom == nil {
+			if fields, err = CustomFields(RequestTenant(r)); err != nil {
+				writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+				return
+			}
+			search.Custom = map[string]interface{}{}
+		}
+		name := k[len("custom[") : len(k)-1]
+		f, ok := fields[name]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", fmt.Sprintf("unknown custom field %v", name))
+			return
+		}
+		if search.Custom[name], err = f.Parse(v.Get(k)); err != nil {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+			return
+		}
+	}
+
+	if sort := splitList(v.Get("sort")); sort != nil {
+		if _, err := sortFields(sort); err != nil {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+			return
+		}
+		search.Sort = sort
+	}
+
+	// Query task.
+	search.Query = v.Get("query")
+	if d := v.Get("done"); d != "" {
+		search.Done, _ = strconv.ParseBool(d)
+		search.All = false
+	}
+	if search.Due == "overdue" && ((!search.All && search.Done) || workflow.IsTerminal(search.Status)) {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", ErrOverdueConflict.Error())
+		return
+	}
+	if search.Actionable && ((!search.All && search.Done) || workflow.IsTerminal(search.Status)) {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", ErrActionableConflict.Error())
+		return
+	}
+
+	tasks, n, err := search.Find()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := LoadTaskRelations(RequestTenant(r), accessUser(r), tasks, params.Include); err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	if err := LoadSubtaskProgress(RequestTenant(r), accessUser(r), tasks); err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	if err := LoadBlocked(RequestTenant(r), tasks); err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	if params.RenderHTML {
+		for _, t := range tasks {
+			t.RenderDescription()
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalManyPayload(w, tasks, n)
+	})
+}
+
+// MoveTaskAPI move a task before or after another one with the `before` or
+// `after` query parameter.
+func MoveTaskAPI(w http.ResponseWriter, r *http.Request) {
+
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	v := r.URL.Query()
+	target, after := v.Get("before"), false
+	if a := v.Get("after"); a != "" {
+		target, after = a, true
+	}
+	if target == "" {
+		writeError(w, http.StatusBadRequest, "Move Error", "before or after parameter is required")
+		return
+	}
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Move Error"); err != nil {
+		return
+	}
+
+	task, err := MoveTask(RequestTenant(r), accessUser(r), RequestUser(r), mux.Vars(r)["sid"], target, after)
+	if err == ErrInvalidTarget {
+		writeError(w, http.StatusBadRequest, "Move Error", err.Error())
+		return
+	} else if err == ErrTitleTaken {
+		writeError(w, http.StatusConflict, "Move Error", err.Error())
+		return
+	} else if err != nil {
+		writeAccessError(w, "Move Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, task)
+}
+
+// DependencyTaskAPI return the task and the tasks it depends on, each task
+// coming after its blockers.
+func DependencyTaskAPI(w http.ResponseWriter, r *http.Request) {
+
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleViewer, "Dependency Error"); err != nil {
+		return
+	}
+
+	tasks, err := DependencyOrder(RequestTenant(r), accessUser(r), mux.Vars(r)["sid"])
+	if err == mgo.ErrNotFound {
+		writeError(w, http.StatusNotFound, "Dependency Error", err.Error())
+		return
+	}
+	if err == ErrDependencyCycle {
+		writeError(w, http.StatusConflict, "Dependency Error", err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Dependency Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, tasks, len(tasks))
+}
+
+// OccurrenceTaskAPI return the next due dates of a recurring task, the
+// `count` parameter set how many (5 by default).
+func OccurrenceTaskAPI(w http.ResponseWriter, r *http.Request) {
+
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	count := 5
+	if c := r.URL.Query().Get("count"); c != "" {
+		n, err := strconv.Atoi(c)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", fmt.Sprintf("invalid count %v", c))
 			return
 		}
+		count = n
+	}
+
+	task, err := SelectTaskAs(RequestTenant(r), accessUser(r), mux.Vars(r)["sid"], nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Occurrence Error", err.Error())
+		return
+	}
+	if !task.ID.Valid() {
+		writeError(w, http.StatusNotFound, "Occurrence Error", "task not found")
+		return
 	}
 
-	jsonapi.MarshalManyPayload(w, tasks, n)
+	occurrences, err := task.Occurrences(count)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Occurrence Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"meta": map[string]interface{}{"occurrences": occurrences},
+	})
 }
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/jsonapi"
 	"github.com/gorilla/mux"
@@ -38,8 +39,8 @@ func TestMockTask(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		task := createTaskOrFatal(t, "search task number "+fmt.Sprintf("%02d", i))
 		if i%2 == 0 {
-			task.Done = true
-			task.Update()
+			task.Status = "done"
+			task.Update("")
 		}
 	}
 }
@@ -49,36 +50,36 @@ const url = "task"
 /*
 func TestHandlerCreateTask(t *testing.T) {
 
-	// Create a request.
-	title := "handler testing task"
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(fmt.Sprintf("title=%v", title)))
-	if err != nil {
-		t.Fatal(err)
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
+		// Create a request.
+		title := "handler testing task"
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(fmt.Sprintf("title=%v", title)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
 
-	// Create a response recorder.
-	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateTask)
+		// Create a response recorder.
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(CreateTask)
 
-	// Serve the request.
-	handler.ServeHTTP(rr, req)
+		// Serve the request.
+		handler.ServeHTTP(rr, req)
 
-	// Test status code.
-	if rr.Code != http.StatusOK {
-		t.Errorf("%v", rr.Body.String())
-	}
+		// Test status code.
+		if rr.Code != http.StatusOK {
+			t.Errorf("%v", rr.Body.String())
+		}
 
-	// Test the errors.
+		// Test the errors.
 
-	// Transform the response to a task.
-	task := &Task{}
-	json.Unmarshal(rr.Body.Bytes(), task)
+		// Transform the response to a task.
+		task := &Task{}
+		json.Unmarshal(rr.Body.Bytes(), task)
 
-	if task.Title != title {
-		t.Errorf("expected title '%v', got '%v'", title, task.Title)
+		if task.Title != title {
+			t.Errorf("expected title '%v', got '%v'", title, task.Title)
+		}
 	}
-}
 */
 func TestHandlerCreateTaskJsonApi(t *testing.T) {
 	// Create a request.
@@ -103,7 +104,7 @@ func TestHandlerCreateTaskJsonApi(t *testing.T) {
 
 func TestHandlerUpdateTaskAPI(t *testing.T) {
 	task, err := NewTask("handler task will be updated")
-	if err := task.Save(); err != nil {
+	if err := task.Save(""); err != nil {
 		t.Errorf("unexpected error (%v)", err)
 	}
 
@@ -126,9 +127,163 @@ func TestHandlerUpdateTaskAPI(t *testing.T) {
 	}
 }
 
+func TestHandlerUpdateTaskListAPI(t *testing.T) {
+	list := createListOrFatal(t, "test update task list api")
+	task := createListTaskOrFatal(t, list, "test update task list api")
+	createListTaskOrFatal(t, list, "test update task list api taken")
+
+	update := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("unexpected error : %v", err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(UpdateTaskAPI).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A title taken in the list is a conflict.
+	rr := update(`{"data": {"type": "task", "id": "` + task.SID + `", "attributes": {"title": "test update task list api taken"}}}`)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+
+	// A null list takes the task out of the list.
+	rr = update(`{"data": {"type": "task", "id": "` + task.SID + `", "attributes": {"title": "test update task list api taken"}, "relationships": {"list": {"data": null}}}}`)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+	if u := selectTaskOrFatal(t, task.SID); u.ListID != "" {
+		t.Errorf("expected no list, got %v", u.ListID)
+	}
+}
+
+func TestHandlerClearTaskRelationsAPI(t *testing.T) {
+	parent := createTaskOrFatal(t, "test clear relations parent")
+	blocker := createTaskOrFatal(t, "test clear relations blocker")
+	task := newTaskOrFatal(t, "test clear relations")
+	task.Parent = parent
+	task.BlockedBy = []*Task{blocker}
+	task.Tags = []*Tag{createTagOrFatal(t, "test clear relations")}
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	body := `{"data": {"type": "task", "id": "` + task.SID + `", "attributes": {"title": "test clear relations"}, "relationships": {"tags": {"data": []}, "blocked_by": {"data": []}, "parent": {"data": null}}}}`
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(UpdateTaskAPI).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+
+	u := selectTaskOrFatal(t, task.SID)
+	if len(u.TagIDs) != 0 || len(u.BlockedByIDs) != 0 || u.ParentID != "" {
+		t.Errorf("expected no relations, got tags %v, blockers %v and parent %v", u.TagIDs, u.BlockedByIDs, u.ParentID)
+	}
+}
+
+func TestHandlerUpdateTaskKeepDueAPI(t *testing.T) {
+	due := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	task := createDueTaskOrFatal(t, "test update task keep due", due)
+
+	body := `{"data": {"type": "task", "id": "` + task.SID + `", "attributes": {"title": "test update task keep due"}}}`
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(UpdateTaskAPI).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+
+	if u := selectTaskOrFatal(t, task.SID); u.DueAt == nil || !u.DueAt.Equal(due) {
+		t.Errorf("expected due date %v, got %v", due, u.DueAt)
+	}
+}
+
+// patchTaskOrFatal update a task with only the given attributes.
+func patchTaskOrFatal(t *testing.T, sid string, attributes string) {
+	body := `{"data": {"type": "task", "id": "` + sid + `", "attributes": ` + attributes + `}}`
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(UpdateTaskAPI).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestHandlerUpdateTaskKeepPriorityAPI(t *testing.T) {
+	task := newTaskOrFatal(t, "test update task keep priority")
+	task.Priority = "high"
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	patchTaskOrFatal(t, task.SID, `{"title": "test update task keep priority"}`)
+
+	if u := selectTaskOrFatal(t, task.SID); u.Priority != "high" || u.PriorityRank != priorityRank("high") {
+		t.Errorf("expected priority high, got %v (%v)", u.Priority, u.PriorityRank)
+	}
+}
+
+func TestHandlerUpdateTaskKeepDescriptionAPI(t *testing.T) {
+	task := newTaskOrFatal(t, "test update task keep description")
+	task.Description = "Some *Markdown* notes"
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	patchTaskOrFatal(t, task.SID, `{"title": "test update task keep description", "done": true}`)
+
+	if u := selectTaskOrFatal(t, task.SID); u.Description != "Some *Markdown* notes" {
+		t.Errorf("expected the description to be kept, got '%v'", u.Description)
+	}
+}
+
+func TestHandlerUpdateTaskKeepEstimateAPI(t *testing.T) {
+	task := newTaskOrFatal(t, "test update task keep estimate")
+	task.EstimateMinutes = 90
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	patchTaskOrFatal(t, task.SID, `{"title": "test update task keep estimate"}`)
+
+	if u := selectTaskOrFatal(t, task.SID); u.EstimateMinutes != 90 {
+		t.Errorf("expected an estimate of 90 minutes, got %v", u.EstimateMinutes)
+	}
+}
+
+func TestHandlerUpdateTaskKeepStatusAPI(t *testing.T) {
+	task := createTaskOrFatal(t, "test update task keep status")
+	task.Status = "done"
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	done := selectTaskOrFatal(t, task.SID)
+
+	patchTaskOrFatal(t, task.SID, `{"title": "test update task keep status renamed"}`)
+
+	u := selectTaskOrFatal(t, task.SID)
+	if !u.Done || u.Status != "done" {
+		t.Errorf("expected a done task, got %v", u.Status)
+	}
+	if u.CompletedAt == nil || !u.CompletedAt.Equal(*done.CompletedAt) {
+		t.Errorf("expected completed at %v, got %v", done.CompletedAt, u.CompletedAt)
+	}
+}
+
 func oldTestHandlerDeleteTaskAPI(t *testing.T) {
 	task, err := NewTask("handler task will be deleted")
-	if err := task.Save(); err != nil {
+	if err := task.Save(""); err != nil {
 		t.Errorf("unexpected error (%v)", err)
 	}
 
@@ -158,7 +313,7 @@ func TestDeleteTaskAPI(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error : %v", err)
 	}
-	if err := task.Save(); err != nil {
+	if err := task.Save(""); err != nil {
 		t.Fatalf("unexpected error : %v", err)
 	}
 
@@ -183,7 +338,7 @@ func TestReadTaskAPI(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error : %v", err)
 	}
-	if err := task.Save(); err != nil {
+	if err := task.Save(""); err != nil {
 		t.Fatalf("unexpected error : %v", err)
 	}
 
@@ -294,3 +449,31 @@ func TestReadWithSearchQueryTaskAPI(t *testing.T) {
 	}
 
 }
+
+// Test Read handler with a sparse fieldset
+func TestReadTaskAPIWithFields(t *testing.T) {
+	task := createTaskOrFatal(t, "test read task api with fields")
+
+	m := mux.NewRouter()
+	m.HandleFunc("/task/{query}", ReadTaskAPI)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/task/"+task.SID+"?fields[task]=done", strings.NewReader(""))
+
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusOK {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+
+	// Test the result data.
+	find := new(Task)
+	if err := jsonapi.UnmarshalPayload(rr.Body, find); err != nil {
+		t.Errorf("unexpected error (%v)", err)
+	}
+
+	if find.Title != "" {
+		t.Errorf("expected no title, got '%v'", find.Title)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errUnknownKey is returned for a key ID missing from the key set.
+var errUnknownKey = errors.New("unknown signing key")
+
+// errUnsupportedKey is returned for a key type or curve not handled, such
+// keys are left out of the key set.
+var errUnsupportedKey = errors.New("unsupported key")
+
+// jwk is a JSON Web Key of a RSA or EC public key.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// KeySet is a cached JSON Web Key Set read from a file or an URL. The keys
+// are reloaded after the TTL, or when a token is signed by an unknown key
+// to follow the rotations.
+type KeySet struct {
+	// Source is a file path or a http(s) URL.
+	Source string
+	TTL    time.Duration
+	// MinRefresh limit the reloads of unknown keys.
+	MinRefresh time.Duration
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey
+	loadedAt time.Time
+	// loading is the reload in progress, nil when none.
+	loading *keyLoad
+}
+
+// keyLoad is a reload of the key set shared by the concurrent callers, done
+// is closed once err is set.
+type keyLoad struct {
+	done chan struct{}
+	err  error
+}
+
+// NewKeySet create a key set reloaded every hour.
+func NewKeySet(source string) *KeySet {
+	return &KeySet{Source: source, TTL: time.Hour, MinRefresh: time.Minute}
+}
+
+// Key return the public key of the key ID.
+func (ks *KeySet) Key(kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	now := time.Now()
+	key, ok := ks.keys[kid]
+	expired := now.Sub(ks.loadedAt) > ks.TTL
+	reload := expired || now.Sub(ks.loadedAt) > ks.MinRefresh
+	ks.mu.Unlock()
+	if ok && !expired {
+		return key, nil
+	}
+
+	// An unknown key reload the set, at most every MinRefresh.
+	if reload {
+		if err := ks.refresh(); err != nil {
+			// Keep the known keys while the source is unavailable.
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		ks.mu.Lock()
+		key, ok = ks.keys[kid]
+		ks.mu.Unlock()
+	}
+	if !ok {
+		return nil, errUnknownKey
+	}
+	return key, nil
+}
+
+// refresh reload the key set without holding the lock during the fetch, the
+// concurrent callers wait for the same reload and get its error.
+func (ks *KeySet) refresh() error {
+	ks.mu.Lock()
+	l := ks.loading
+	if l == nil {
+		l = &keyLoad{done: make(chan struct{})}
+		ks.loading = l
+		ks.mu.Unlock()
+
+		keys, err := ks.load()
+
+		ks.mu.Lock()
+		if err == nil {
+			ks.keys, ks.loadedAt = keys, time.Now()
+		}
+		ks.loading = nil
+		l.err = err
+		close(l.done)
+	}
+	ks.mu.Unlock()
+
+	<-l.done
+	return l.err
+}
+
+// load read and parse the key set.
+func (ks *KeySet) load() (map[string]crypto.PublicKey, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(ks.Source, "http://") || strings.HasPrefix(ks.Source, "https://") {
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Get(ks.Source)
+		if err != nil {
+			return nil, fmt.Errorf("can't to fetch the key set (%v)", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("can't to fetch the key set (status %v)", resp.StatusCode)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(ks.Source)
+		if err != nil {
+			return nil, fmt.Errorf("can't to read the key set (%v)", err)
+		}
+		r = f
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(r, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return parseKeySet(b)
+}
+
+// parseKeySet parse the signing keys of a JSON Web Key Set.
+func parseKeySet(b []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("invalid key set (%v)", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err == errUnsupportedKey {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %v (%v)", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey decode the RSA or EC public key.
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errUnsupportedKey
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, errUnsupportedKey
+}
+
+// decodeBigInt decode a base64url big-endian integer.
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
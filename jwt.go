@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jwtAlgorithms is the accepted signature algorithms with their hash.
+var jwtAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"PS256": crypto.SHA256,
+	"PS384": crypto.SHA384,
+	"PS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// roleScopes is the API scopes granted by the roles of the token.
+var roleScopes = map[string][]string{
+	"admin":  {ScopeAdmin},
+	"editor": {ScopeTasksWrite},
+	"viewer": {ScopeTasksRead},
+}
+
+// jwtHeader is the JOSE header of a token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// audience is the aud claim, a string or a list of strings.
+type audience []string
+
+// UnmarshalJSON read a single or a list of audiences.
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*a = l
+	return nil
+}
+
+// jwtClaims is the claims of a token used by the API.
+type jwtClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	ExpiresAt         float64  `json:"exp"`
+	NotBefore         float64  `json:"nbf"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Email             string   `json:"email"`
+	Roles             []string `json:"roles"`
+	Scope             string   `json:"scope"`
+	Tenant            string   `json:"tenant"`
+}
+
+// JWTAuth authenticate the bearer JSON Web Tokens of an OpenID Connect
+// provider. The subject is mapped to a user created on its first request.
+type JWTAuth struct {
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew tolerated on the time claims.
+	Leeway time.Duration
+}
+
+// Authenticate verify the token of the credentials and return the identity of
+// its subject.
+func (ja *JWTAuth) Authenticate(r *http.Request) (*Identity, error) {
+	token := credentials(r)
+	if strings.Count(token, ".") != 2 {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := ja.verify(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.PreferredUsername
+	if name == "" {
+		name = claims.Name
+	}
+	if _, err := lookupTenant(claims.Tenant); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	user, err := userForSubject(claims.Tenant, claims.Subject, name, claims.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	id := &Identity{UserID: user.SID, Tenant: claims.Tenant}
+	for _, role := range claims.Roles {
+		id.Scopes = append(id.Scopes, roleScopes[role]...)
+	}
+	for _, scope := range strings.Fields(claims.Scope) {
+		if scope == ScopeTasksRead || scope == ScopeTasksWrite {
+			id.Scopes = append(id.Scopes, scope)
+		}
+	}
+	return id, nil
+}
+
+// verify checks the signature and the registered claims of the token.
+func (ja *JWTAuth) verify(token string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+
+	header := &jwtHeader{}
+	if err := decodeSegment(parts[0], header); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	hash, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	key, err := ja.Keys.Key(header.Kid)
+	if err == errUnknownKey {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if !verifySignature(header.Alg, key, hash, h.Sum(nil), sig) {
+		return nil, ErrInvalidCredentials
+	}
+
+	claims := &jwtClaims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if claims.Subject == "" || claims.Issuer != ja.Issuer || !contains(claims.Audience, ja.Audience) {
+		return nil, ErrInvalidCredentials
+	}
+	unix := float64(now.Unix())
+	leeway := ja.Leeway.Seconds()
+	if claims.ExpiresAt == 0 || unix > claims.ExpiresAt+leeway || unix < claims.NotBefore-leeway {
+		return nil, ErrInvalidCredentials
+	}
+	return claims, nil
+}
+
+// verifySignature checks the signature with the key of the algorithm family.
+func verifySignature(alg string, key crypto.PublicKey, hash crypto.Hash, digest []byte, sig []byte) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") {
+			return rsa.VerifyPKCS1v15(k, hash, digest, sig) == nil
+		}
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(k, hash, digest, sig, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(sig) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(k, digest, r, s)
+	}
+	return false
+}
+
+// decodeSegment decode a base64url JSON segment of a token.
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
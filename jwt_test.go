@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// signToken sign the claims with the RSA or EC key.
+func signToken(t *testing.T, alg string, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	seg := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	input := seg(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + seg(claims)
+
+	hash := jwtAlgorithms[alg]
+	h := hash.New()
+	h.Write([]byte(input))
+
+	var sig []byte
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, hash, h.Sum(nil))
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, h.Sum(nil))
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[size-len(rb):size], rb)
+		copy(sig[2*size-len(sb):], sb)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// keySetJSON return the JSON Web Key Set of the public keys.
+func keySetJSON(keys map[string]crypto.Signer) []byte {
+	enc := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	var set []map[string]string
+	for kid, key := range keys {
+		switch k := key.Public().(type) {
+		case *rsa.PublicKey:
+			set = append(set, map[string]string{"kid": kid, "kty": "RSA", "n": enc(k.N.Bytes()), "e": enc(big.NewInt(int64(k.E)).Bytes())})
+		case *ecdsa.PublicKey:
+			set = append(set, map[string]string{"kid": kid, "kty": "EC", "crv": "P-256", "x": enc(k.X.Bytes()), "y": enc(k.Y.Bytes())})
+		}
+	}
+	b, _ := json.Marshal(map[string]interface{}{"keys": set})
+	return b
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss": "https://sso.example.com",
+		"aud": []string{"task-api"},
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWTVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	f, err := ioutil.TempFile("", "jwks")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(keySetJSON(map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey}))
+	f.Close()
+
+	ja := &JWTAuth{Keys: NewKeySet(f.Name()), Issuer: "https://sso.example.com", Audience: "task-api"}
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name   string
+		token  string
+		expect error
+	}{
+		{"rsa", signToken(t, "RS256", "rsa", rsaKey, validClaims()), nil},
+		{"ec", signToken(t, "ES256", "ec", ecKey, validClaims()), nil},
+		{"wrong key", signToken(t, "RS256", "rsa", ecKey, validClaims()), ErrInvalidCredentials},
+		{"unknown key", signToken(t, "RS256", "other", rsaKey, validClaims()), ErrInvalidCredentials},
+	} {
+		if _, err := ja.verify(tc.token, now); err != tc.expect {
+			t.Errorf("%v: expected %v, got %v", tc.name, tc.expect, err)
+		}
+	}
+
+	for name, change := range map[string]func(map[string]interface{}){
+		"issuer":   func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" },
+		"audience": func(c map[string]interface{}) { c["aud"] = "other-api" },
+		"expired":  func(c map[string]interface{}) { c["exp"] = now.Add(-time.Hour).Unix() },
+		"subject":  func(c map[string]interface{}) { delete(c, "sub") },
+	} {
+		claims := validClaims()
+		change(claims)
+		if _, err := ja.verify(signToken(t, "RS256", "rsa", rsaKey, claims), now); err != ErrInvalidCredentials {
+			t.Errorf("%v: expected %v, got %v", name, ErrInvalidCredentials, err)
+		}
+	}
+
+	none := "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"x"}`)) + "."
+	if _, err := ja.verify(none, now); err != ErrInvalidCredentials {
+		t.Errorf("expected the none algorithm to be rejected, got %v", err)
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	keys := map[string]crypto.Signer{"old": oldKey}
+	loads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loads++
+		w.Write(keySetJSON(keys))
+	}))
+	defer srv.Close()
+
+	ks := NewKeySet(srv.URL)
+	ks.MinRefresh = 0
+	if _, err := ks.Key("old"); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	// The provider rotate its key.
+	keys = map[string]crypto.Signer{"new": newKey}
+	if _, err := ks.Key("new"); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if _, err := ks.Key("new"); err != nil || loads != 2 {
+		t.Errorf("expected the key set to be cached, got %v loads (%v)", loads, err)
+	}
+	if _, err := ks.Key("missing"); err != errUnknownKey {
+		t.Errorf("expected error %v, got %v", errUnknownKey, err)
+	}
+}
+
+func TestKeySetConcurrentReload(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var loads int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		w.Write(keySetJSON(map[string]crypto.Signer{"key": key}))
+	}))
+	defer srv.Close()
+
+	ks := NewKeySet(srv.URL)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ks.Key("key"); err != nil {
+				t.Errorf("unexpected error : %v", err)
+			}
+		}()
+	}
+
+	// The lock is free during the fetch.
+	time.Sleep(50 * time.Millisecond)
+	ks.mu.Lock()
+	ks.mu.Unlock()
+
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Errorf("expected a single load, got %v", n)
+	}
+}
+
+func TestKeySetUnsupportedKeys(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var set map[string][]map[string]string
+	json.Unmarshal(keySetJSON(map[string]crypto.Signer{"rsa": key}), &set)
+	set["keys"] = append(set["keys"],
+		map[string]string{"kid": "okp", "kty": "OKP", "crv": "Ed25519", "x": "AA"},
+		map[string]string{"kid": "ec", "kty": "EC", "crv": "secp256k1", "x": "AA", "y": "AA"})
+	b, _ := json.Marshal(set)
+
+	keys, err := parseKeySet(b)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(keys) != 1 || keys["rsa"] == nil {
+		t.Errorf("expected only the RSA key, got %v", keys)
+	}
+}
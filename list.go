@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrListNotEmpty is returned when deleting a list which still have tasks.
+var ErrListNotEmpty = errors.New("list still have tasks")
+
+// List is a project grouping tasks.
+type List struct {
+	ID        bson.ObjectId `bson:"_id,omitempty"`
+	SID       string        `bson:"sid,omitempty" jsonapi:"primary,list"`
+	Title     string        `bson:"title" validate:"required" jsonapi:"attr,title"`
+	CreatedAt time.Time     `bson:"createdAt" jsonapi:"attr,created_at"`
+	// CreatedByID owns the list, Shares is only changed by the share
+	// operations.
+	CreatedByID string   `bson:"createdBy,omitempty"`
+	Shares      []*Share `bson:"shares,omitempty"`
+}
+
+func init() {
+	resourceTypes["list"] = List{}
+	taskRelations["list"] = loadTaskList
+}
+
+// NewList create a new list.
+func NewList(title string) (*List, error) {
+	list := &List{Title: title, CreatedAt: time.Now()}
+	if err := list.Validate(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Validate checks attributes's integrity.
+func (l *List) Validate() error {
+	err := validator.New().Struct(l)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// listKey return the value matching the tasks of a list in a query, tasks
+// out of any list have no list field.
+func listKey(id string) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
+
+// Save persist the list into the database.
+func (l *List) Save(tenant string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "lists")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	l.ID = bson.NewObjectId()
+	l.SID = l.ID.Hex()
+	l.CreatedAt = time.Now()
+
+	if err := c.Insert(l); err != nil {
+		return fmt.Errorf("can't to persist the list (%v)", err)
+	}
+	return nil
+}
+
+// Update rename an existing list.
+func (l *List) Update(tenant string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "lists")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(l.SID) {
+		return fmt.Errorf("ID is required for update list")
+	}
+	l.ID = bson.ObjectIdHex(l.SID)
+
+	if err := c.UpdateId(l.ID, bson.M{"$set": bson.M{"title": l.Title}}); err != nil {
+		return fmt.Errorf("can't to persist the list (%v)", err)
+	}
+	return c.FindId(l.ID).One(l)
+}
+
+// SelectList find a list by ID.
+func SelectList(tenant string, id string) (*List, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "lists")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, fmt.Errorf("id value is not valid (%v)", id)
+	}
+
+	l := &List{}
+	if err := c.FindId(bson.ObjectIdHex(id)).One(l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// SearchLists return all the lists sorted by title.
+func SearchLists(tenant string) ([]*List, error) {
+	return SearchListsAs(tenant, "")
+}
+
+// SearchListsAs return the lists the user created or is shared sorted by
+// title, an empty user sees every list.
+func SearchListsAs(tenant string, user string) ([]*List, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "lists")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	var q bson.M
+	if user != "" {
+		q = bson.M{"$or": []bson.M{{"createdBy": user}, {"shares.user": user}}}
+	}
+
+	lists := []*List{}
+	if err := c.Find(q).Sort("title", "_id").All(&lists); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+	return lists, nil
+}
+
+// DeleteList remove a list. A list with tasks is only removed with cascade,
+// which removes its tasks like DeleteTaskTree, their subtasks in other lists
+// are moved under their parent. The removals are recorded in the audit as
+// done by the actor.
+func DeleteList(tenant string, actor string, id string, cascade bool) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "lists")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return fmt.Errorf("id value is not valid (%v)", id)
+	}
+
+	var ids []string
+	if err := c.Database.C("tasks").Find(bson.M{"list": id}).Distinct("sid", &ids); err != nil {
+		return err
+	}
+	if len(ids) > 0 && !cascade {
+		return ErrListNotEmpty
+	}
+	for _, sid := range ids {
+		if err := DeleteTaskTreeAs(tenant, "", actor, sid, false); err != nil && err != mgo.ErrNotFound {
+			return fmt.Errorf("can't to remove the task %v (%v)", sid, err)
+		}
+	}
+
+	return c.RemoveId(bson.ObjectIdHex(id))
+}
+
+// resolveList checks the list of the relationship exists and set the stored
+// list ID, a null list takes the task out of its list.
+func (t *Task) resolveList(tenant string) error {
+	if t.List == nil {
+		return nil
+	}
+	if t.List.SID == "" {
+		t.ListID = ""
+		return nil
+	}
+	if _, err := SelectList(tenant, t.List.SID); err != nil {
+		return fmt.Errorf("unknown list %v (%v)", t.List.SID, err)
+	}
+	t.ListID = t.List.SID
+	return nil
+}
+
+// loadTaskList fill the list relationship of the tasks when the user can view
+// the list, a task can be shared without its list.
+func loadTaskList(tenant string, user string, tasks []*Task) error {
+	lists := map[string]*List{}
+	for _, t := range tasks {
+		if t.ListID == "" {
+			continue
+		}
+		if _, ok := lists[t.ListID]; !ok {
+			l, err := SelectList(tenant, t.ListID)
+			if err != nil {
+				return err
+			}
+			if listRole(l, user) == "" {
+				l = nil
+			}
+			lists[t.ListID] = l
+		}
+		if l := lists[t.ListID]; l != nil {
+			t.List = l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+)
+
+// CreateListAPI create a new list with jsonapi params.
+func CreateListAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	list := new(List)
+	if err := populateModel(r.Body, w, list); err != nil {
+		return
+	}
+
+	if err := validateModel(list, w); err != nil {
+		return
+	}
+	list.CreatedByID = RequestUser(r)
+	list.Shares = nil
+
+	// Save the list.
+	if err := list.Save(RequestTenant(r)); err != nil {
+		writeError(w, http.StatusInternalServerError, "Save Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, list)
+}
+
+// UpdateListAPI rename a list.
+func UpdateListAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	list := new(List)
+	if err := populateModel(r.Body, w, list); err != nil {
+		return
+	}
+
+	if err := validateModel(list, w); err != nil {
+		return
+	}
+
+	if err := authorizeList(w, r, list.SID, RoleEditor, "Update Error"); err != nil {
+		return
+	}
+
+	// Update the list.
+	if err := list.Update(RequestTenant(r)); err != nil {
+		writeError(w, http.StatusInternalServerError, "Update Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, list)
+}
+
+// ReadListAPI return a list.
+func ReadListAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	params, err := ParseParams(r, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	if err := authorizeList(w, r, mux.Vars(r)["list"], RoleViewer, "Read Error"); err != nil {
+		return
+	}
+
+	list, err := SelectList(RequestTenant(r), mux.Vars(r)["list"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Read Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalOnePayload(w, list)
+	})
+}
+
+// SearchListAPI return all the lists.
+func SearchListAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	params, err := ParseParams(r, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	lists, err := SearchListsAs(RequestTenant(r), accessUser(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalManyPayload(w, lists, len(lists))
+	})
+}
+
+// DeleteListAPI remove a list and return a 204 (no-content) response. A list
+// with tasks is rejected with a 409 unless the `cascade` parameter is true.
+func DeleteListAPI(w http.ResponseWriter, r *http.Request) {
+
+	if err := authorizeList(w, r, mux.Vars(r)["list"], RoleOwner, "Delete Error"); err != nil {
+		return
+	}
+
+	cascade, _ := strconv.ParseBool(r.URL.Query().Get("cascade"))
+
+	err := DeleteList(RequestTenant(r), RequestUser(r), mux.Vars(r)["list"], cascade)
+	if err == ErrListNotEmpty {
+		writeError(w, http.StatusConflict, "Delete Error", err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Delete Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func createListOrFatal(t *testing.T, title string) *List {
+	list, err := NewList(title)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := list.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return list
+}
+
+func createListTaskOrFatal(t *testing.T, list *List, title string) *Task {
+	task := newTaskOrFatal(t, title)
+	task.List = list
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return task
+}
+
+func TestNewListWithEmptyTitle(t *testing.T) {
+	if _, err := NewList(""); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestSaveSameTitleInTwoLists(t *testing.T) {
+	home := createListOrFatal(t, "test list home")
+	work := createListOrFatal(t, "test list work")
+
+	createListTaskOrFatal(t, home, "test task in two lists")
+	createListTaskOrFatal(t, work, "test task in two lists")
+
+	task := newTaskOrFatal(t, "test task in two lists")
+	task.List = home
+	if err := task.Save(""); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestRemoveTaskFromList(t *testing.T) {
+	list := createListOrFatal(t, "test remove task from list")
+	task := createListTaskOrFatal(t, list, "test remove task from list task")
+
+	task.List = &List{}
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if find := selectTaskOrFatal(t, task.SID); find.ListID != "" {
+		t.Errorf("expected the task out of the list, got %v", find.ListID)
+	}
+}
+
+func TestDeleteNotEmptyList(t *testing.T) {
+	list := createListOrFatal(t, "test list not empty")
+	task := createListTaskOrFatal(t, list, "test task of a deleted list")
+
+	if err := DeleteList("", "", list.SID, false); err != ErrListNotEmpty {
+		t.Errorf("expected error %v, got %v", ErrListNotEmpty, err)
+	}
+
+	if err := DeleteList("", "", list.SID, true); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if find := selectTaskOrFatal(t, task.SID); find.ID.Valid() {
+		t.Errorf("expected the task to be removed, got %v", find.SID)
+	}
+}
+
+func TestDeleteListCleanup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer os.RemoveAll(dir)
+	blobs = &FileStore{Dir: dir}
+
+	list := createListOrFatal(t, "test delete list cleanup")
+	task := newTaskOrFatal(t, "test delete list cleanup task")
+	task.List = list
+	task.DueDate = time.Now().Add(48 * time.Hour).Format(time.RFC3339)
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	blocked := createBlockedTaskOrFatal(t, "test delete list cleanup blocked", task)
+
+	r := &Reminder{TaskID: task.SID, Before: 60}
+	if err := r.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	createCommentOrFatal(t, task, "alice", "a comment of a deleted list")
+	a, err := SaveAttachment("", task.SID, "notes.txt", strings.NewReader("deleted list content"))
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := DeleteList("", "", list.SID, true); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if reminders, _ := SearchReminders("", task.SID); len(reminders) != 0 {
+		t.Errorf("expected the reminders to be removed, got %v", len(reminders))
+	}
+	if _, n, _ := SearchComments("", task.SID, 1, 10); n != 0 {
+		t.Errorf("expected the comments to be archived, got %v", n)
+	}
+	if attachments, _ := SearchAttachments("", task.SID); len(attachments) != 0 {
+		t.Errorf("expected the attachments to be removed, got %v", len(attachments))
+	}
+	if _, err := blobs.Open(a.Hash); !os.IsNotExist(err) {
+		t.Errorf("expected the content to be removed, got %v", err)
+	}
+	if find := selectTaskOrFatal(t, blocked.SID); len(find.BlockedByIDs) != 0 {
+		t.Errorf("expected the task to be unblocked, got %v", find.BlockedByIDs)
+	}
+	if entries, _ := TaskHistory("", task.SID); len(entries) == 0 || entries[len(entries)-1].Action != AuditDelete {
+		t.Errorf("expected the removal in the audit, got %v", entries)
+	}
+}
+
+func TestListTasksAPI(t *testing.T) {
+	list := createListOrFatal(t, "test list tasks api")
+	createListTaskOrFatal(t, list, "test task of a nested route")
+
+	m := mux.NewRouter()
+	m.HandleFunc("/lists/{list}/tasks", SearchTaskAPI)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/lists/"+list.SID+"/tasks", strings.NewReader(""))
+
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusOK {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "test task of a nested route") {
+		t.Errorf("expected the task of the list, got %v", rr.Body.String())
+	}
+}
+
+func TestSearchListAPIWithFields(t *testing.T) {
+	createListOrFatal(t, "test search list api with fields")
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/lists/?fields[list]=title", strings.NewReader(""))
+
+	http.HandlerFunc(SearchListAPI).ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "test search list api with fields") {
+		t.Errorf("expected the title of the list, got %v", rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "created_at") {
+		t.Errorf("expected no creation date, got %v", rr.Body.String())
+	}
+}
+
+func TestSearchListAPIWithUnknownField(t *testing.T) {
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/lists/?fields[list]=unknown", strings.NewReader(""))
+
+	http.HandlerFunc(SearchListAPI).ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
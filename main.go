@@ -2,9 +2,15 @@ package main
 
 import (
 	"log"
+	"net"
 	"net/http"
 
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -17,6 +23,74 @@ func main() {
 		log.Fatalln("Env var TASK_DB is not define!")
 	}
 
+	// Load the tenants, each one have its own database.
+	if path := os.Getenv("TASK_TENANTS"); path != "" {
+		ts, err := LoadTenants(path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, t := range ts {
+			if t.Database == os.Getenv("TASK_DB") {
+				log.Fatalf("Tenant %v can't use the default database %v", t.Name, t.Database)
+			}
+		}
+		tenants = ts
+	}
+
+	// Load a custom workflow.
+	if path := os.Getenv("TASK_WORKFLOW"); path != "" {
+		wf, err := LoadWorkflow(path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		workflow = wf
+	}
+
+	// Define the behavior when completing a task with open subtasks.
+	if policy := os.Getenv("TASK_OPEN_SUBTASKS"); policy != "" {
+		if policy != OpenSubtasksReject && policy != OpenSubtasksComplete && policy != OpenSubtasksAllow {
+			log.Fatalf("Env var TASK_OPEN_SUBTASKS must be %v, %v or %v", OpenSubtasksReject, OpenSubtasksComplete, OpenSubtasksAllow)
+		}
+		openSubtasksPolicy = policy
+	}
+
+	// Define where the attachments content is stored.
+	switch store := os.Getenv("TASK_BLOB_STORE"); store {
+	case "", "file":
+		if dir := os.Getenv("TASK_BLOB_DIR"); dir != "" {
+			blobs = &FileStore{Dir: dir}
+		}
+	case "gridfs":
+		blobs = &GridFSStore{Prefix: "blobs"}
+	default:
+		log.Fatalf("Env var TASK_BLOB_STORE must be file or gridfs")
+	}
+
+	// Define the attachments limits.
+	if max := os.Getenv("TASK_MAX_ATTACHMENT"); max != "" {
+		n, err := strconv.ParseInt(max, 10, 64)
+		if err != nil || n < 1 {
+			log.Fatalln("Env var TASK_MAX_ATTACHMENT must be a positive number of bytes")
+		}
+		maxAttachmentSize = n
+	}
+	if types := os.Getenv("TASK_ATTACHMENT_TYPES"); types != "" {
+		attachmentTypes = splitList(types)
+	}
+
+	// Start the reminders scheduler.
+	if url := os.Getenv("TASK_WEBHOOK_URL"); url != "" {
+		notifiers["webhook"] = &WebhookNotifier{URL: url}
+	}
+	if addr := os.Getenv("TASK_SMTP_ADDR"); addr != "" {
+		notifiers["smtp"] = &SMTPNotifier{
+			Addr: addr,
+			From: os.Getenv("TASK_SMTP_FROM"),
+			To:   strings.Split(os.Getenv("TASK_SMTP_TO"), ","),
+		}
+	}
+	go NewScheduler(notifiers).Run(nil)
+
 	r := mux.NewRouter()
 	// Routes consist of a path and a handler function.
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -27,10 +101,177 @@ func main() {
 	r.HandleFunc("/task/", CreateTaskAPI).Methods(http.MethodPost)
 	r.HandleFunc("/task/", UpdateTaskAPI).Methods(http.MethodPatch)
 	r.HandleFunc("/task/{sid}", DeleteTaskAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/task/{sid}/move", MoveTaskAPI).Methods(http.MethodPost)
+	r.HandleFunc("/task/{parent}/subtasks", SearchTaskAPI).Methods(http.MethodGet)
+	r.HandleFunc("/task/{parent}/subtasks", CreateTaskAPI).Methods(http.MethodPost)
+	r.HandleFunc("/task/{sid}/dependencies", DependencyTaskAPI).Methods(http.MethodGet)
+	r.HandleFunc("/task/{sid}/occurrences", OccurrenceTaskAPI).Methods(http.MethodGet)
+	r.HandleFunc("/task/{sid}/reminders", SearchReminderAPI).Methods(http.MethodGet)
+	r.HandleFunc("/task/{sid}/reminders", CreateReminderAPI).Methods(http.MethodPost)
+	r.HandleFunc("/reminders/{sid}", DeleteReminderAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/task/{sid}/checklist", ReadChecklistAPI).Methods(http.MethodGet)
+	r.HandleFunc("/task/{sid}/checklist", AddChecklistAPI).Methods(http.MethodPost)
+	r.HandleFunc("/task/{sid}/checklist", ReorderChecklistAPI).Methods(http.MethodPatch)
+	r.HandleFunc("/task/{sid}/checklist/{item}", EditChecklistAPI).Methods(http.MethodPatch)
+	r.HandleFunc("/task/{sid}/checklist/{item}", RemoveChecklistAPI).Methods(http.MethodDelete)
+	// The comments of a task are under /task/ like its other resources.
+	r.HandleFunc("/task/{sid}/comments", SearchCommentAPI).Methods(http.MethodGet)
+	r.HandleFunc("/task/{sid}/comments", CreateCommentAPI).Methods(http.MethodPost)
+	r.HandleFunc("/comments/{sid}", EditCommentAPI).Methods(http.MethodPatch)
+	r.HandleFunc("/comments/{sid}", DeleteCommentAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/task/{sid}/attachments", SearchAttachmentAPI).Methods(http.MethodGet)
+	r.HandleFunc("/task/{sid}/attachments", CreateAttachmentAPI).Methods(http.MethodPost)
+	r.HandleFunc("/attachments/{sid}/content", DownloadAttachmentAPI).Methods(http.MethodGet)
+	r.HandleFunc("/attachments/{sid}", DeleteAttachmentAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/task/{sid}/shares", SearchTaskShareAPI).Methods(http.MethodGet)
+	r.HandleFunc("/task/{sid}/shares", ShareTaskAPI).Methods(http.MethodPost)
+	r.HandleFunc("/task/{sid}/shares/{user}", UnshareTaskAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/task/{sid}/timer/start", StartTimerAPI).Methods(http.MethodPost)
+	r.HandleFunc("/task/{sid}/timer/stop", StopTimerAPI).Methods(http.MethodPost)
+	r.HandleFunc("/task/{sid}/time-entries", SearchTimeEntryAPI).Methods(http.MethodGet)
+	r.HandleFunc("/task/{sid}/time-entries", CreateTimeEntryAPI).Methods(http.MethodPost)
+	r.HandleFunc("/task/{sid}/history", TaskHistoryAPI).Methods(http.MethodGet)
+	r.HandleFunc("/reports/time", TimeReportAPI).Methods(http.MethodGet)
+	r.HandleFunc("/tags/", SearchTagAPI).Methods(http.MethodGet)
+	r.HandleFunc("/tags/{sid}", ReadTagAPI).Methods(http.MethodGet)
+	r.HandleFunc("/tags/", CreateTagAPI).Methods(http.MethodPost)
+	r.HandleFunc("/tags/", UpdateTagAPI).Methods(http.MethodPatch)
+	r.HandleFunc("/tags/{sid}", DeleteTagAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/admin/api-keys", SearchAPIKeyAPI).Methods(http.MethodGet)
+	r.HandleFunc("/admin/api-keys", MintAPIKeyAPI).Methods(http.MethodPost)
+	r.HandleFunc("/admin/api-keys/{sid}", RevokeAPIKeyAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/admin/audit", SearchAuditAPI).Methods(http.MethodGet)
+	r.HandleFunc("/users/", SearchUserAPI).Methods(http.MethodGet)
+	r.HandleFunc("/users/{sid}", ReadUserAPI).Methods(http.MethodGet)
+	r.HandleFunc("/users/", CreateUserAPI).Methods(http.MethodPost)
+	r.HandleFunc("/users/{sid}", DeleteUserAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/fields/", SearchFieldAPI).Methods(http.MethodGet)
+	r.HandleFunc("/fields/{sid}", ReadFieldAPI).Methods(http.MethodGet)
+	r.HandleFunc("/fields/", CreateFieldAPI).Methods(http.MethodPost)
+	r.HandleFunc("/fields/", UpdateFieldAPI).Methods(http.MethodPatch)
+	r.HandleFunc("/fields/{sid}", DeleteFieldAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/lists/", SearchListAPI).Methods(http.MethodGet)
+	r.HandleFunc("/lists/{list}", ReadListAPI).Methods(http.MethodGet)
+	r.HandleFunc("/lists/", CreateListAPI).Methods(http.MethodPost)
+	r.HandleFunc("/lists/", UpdateListAPI).Methods(http.MethodPatch)
+	r.HandleFunc("/lists/{list}", DeleteListAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/lists/{list}/shares", SearchListShareAPI).Methods(http.MethodGet)
+	r.HandleFunc("/lists/{list}/shares", ShareListAPI).Methods(http.MethodPost)
+	r.HandleFunc("/lists/{list}/shares/{user}", UnshareListAPI).Methods(http.MethodDelete)
+	r.HandleFunc("/lists/{list}/tasks", SearchTaskAPI).Methods(http.MethodGet)
+	r.HandleFunc("/lists/{list}/tasks", CreateTaskAPI).Methods(http.MethodPost)
+
+	// Limit the requests of each client, the limits are shared by the
+	// instances with the mongo store.
+	limiter := &RateLimiter{Store: NewMemoryLimitStore(), Read: Limit{Burst: 600, Period: time.Minute}, Write: Limit{Burst: 120, Period: time.Minute}, IP: Limit{Burst: 1200, Period: time.Minute}}
+	for env, limit := range map[string]*Limit{"TASK_RATE_READ": &limiter.Read, "TASK_RATE_WRITE": &limiter.Write, "TASK_RATE_IP": &limiter.IP} {
+		if value := os.Getenv(env); value != "" {
+			l, err := ParseLimit(value)
+			if err != nil {
+				log.Fatalf("Env var %v is not valid (%v)", env, err)
+			}
+			*limit = l
+		}
+	}
+	switch store := os.Getenv("TASK_RATE_STORE"); store {
+	case "", "memory":
+	case "mongo":
+		limiter.Store = &MongoLimitStore{Collection: "rateLimits"}
+	default:
+		log.Fatalf("Env var TASK_RATE_STORE must be memory or mongo")
+	}
+	r.Use(limiter.Middleware)
+
+	// Authenticate the requests, the admin key mint the first API keys.
+	auth := Authenticators{&StaticKey{Key: os.Getenv("TASK_ADMIN_KEY")}, APIKeyAuth{}}
+	if jwks := os.Getenv("TASK_JWKS"); jwks != "" {
+		issuer, audience := os.Getenv("TASK_JWT_ISSUER"), os.Getenv("TASK_JWT_AUDIENCE")
+		if issuer == "" || audience == "" {
+			log.Fatalln("Env vars TASK_JWT_ISSUER and TASK_JWT_AUDIENCE are required with TASK_JWKS")
+		}
+		auth = append(auth, &JWTAuth{Keys: NewKeySet(jwks), Issuer: issuer, Audience: audience, Leeway: time.Minute})
+	}
+	// The client certificates are verified against the client CA.
+	if os.Getenv("TASK_TLS_CLIENT_CA") != "" {
+		auth = append(auth, ClientCertAuth{})
+	}
+
+	// Allow the browser clients of other origins, the preflights are answered
+	// before the authentication.
+	var handler http.Handler = RequireAuth(auth, r)
+	if origins := os.Getenv("TASK_CORS_ORIGINS"); origins != "" {
+		cors := NewCORS(splitList(origins))
+		if methods := os.Getenv("TASK_CORS_METHODS"); methods != "" {
+			cors.Methods = splitList(strings.ToUpper(methods))
+		}
+		if headers := os.Getenv("TASK_CORS_HEADERS"); headers != "" {
+			cors.Headers = splitList(headers)
+		}
+		cors.Credentials = os.Getenv("TASK_CORS_CREDENTIALS") == "true"
+		if maxAge := os.Getenv("TASK_CORS_MAX_AGE"); maxAge != "" {
+			n, err := strconv.Atoi(maxAge)
+			if err != nil || n < 0 {
+				log.Fatalln("Env var TASK_CORS_MAX_AGE must be a number of seconds")
+			}
+			cors.MaxAge = time.Duration(n) * time.Second
+		}
+		if err := cors.Validate(); err != nil {
+			log.Fatalln("Env var TASK_CORS_ORIGINS can't be * with TASK_CORS_CREDENTIALS")
+		}
+		handler = cors.Handler(handler)
+	}
+
+	// Limit every request by IP, including the ones rejected by the
+	// authentication or not matching any route.
+	handler = limiter.IPMiddleware(handler)
 
 	// Define the logger system.
-	loggerRouter := handlers.LoggingHandler(os.Stdout, r)
+	loggerRouter := handlers.LoggingHandler(os.Stdout, handler)
 
-	// Bind to a port and pass our router in
-	log.Fatal(http.ListenAndServe(":8000", loggerRouter))
+	// Serve plain HTTP without certificate.
+	certFile, keyFile := os.Getenv("TASK_TLS_CERT"), os.Getenv("TASK_TLS_KEY")
+	if certFile == "" && keyFile == "" {
+		// Bind to a port and pass our router in
+		log.Fatal(http.ListenAndServe(":8000", loggerRouter))
+	}
+	if certFile == "" || keyFile == "" {
+		log.Fatalln("Env vars TASK_TLS_CERT and TASK_TLS_KEY are both required")
+	}
+
+	// Serve HTTPS and HTTP/2, the certificate is reloaded on SIGHUP or when
+	// its files change.
+	certs, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	go certs.Watch(time.Minute, nil)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := certs.Reload(); err != nil {
+				log.Printf("tls: %v", err)
+			}
+		}
+	}()
+	config, err := NewTLSConfig(certs, os.Getenv("TASK_TLS_CLIENT_CA"))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	addr := os.Getenv("TASK_TLS_ADDR")
+	if addr == "" {
+		addr = ":8443"
+	}
+	if redirect := os.Getenv("TASK_HTTP_REDIRECT_ADDR"); redirect != "" {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			log.Fatalf("Env var TASK_TLS_ADDR is not valid (%v)", err)
+		}
+		go func() {
+			log.Fatal(http.ListenAndServe(redirect, RedirectHTTPS(port)))
+		}()
+	}
+	server := &http.Server{Addr: addr, Handler: loggerRouter, TLSConfig: config}
+	log.Fatal(server.ListenAndServeTLS("", ""))
 }
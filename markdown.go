@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday"
+)
+
+// renderMarkdown convert a Markdown text to a sanitized HTML fragment.
+func renderMarkdown(md string) string {
+	unsafe := blackfriday.MarkdownCommon([]byte(md))
+	return string(bluemonday.UGCPolicy().SanitizeBytes(unsafe))
+}
+
+// RenderDescription fill the HTML rendering of the task description.
+func (t *Task) RenderDescription() {
+	t.DescriptionHTML = renderMarkdown(t.Description)
+}
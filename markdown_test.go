@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	html := renderMarkdown("**bold** <script>alert(1)</script>")
+	if !strings.Contains(html, "<strong>bold</strong>") {
+		t.Errorf("expected rendered markdown, got '%v'", html)
+	}
+	if strings.Contains(html, "<script>") {
+		t.Errorf("expected sanitized html, got '%v'", html)
+	}
+}
+
+func TestDescriptionTooLong(t *testing.T) {
+	task := newTaskOrFatal(t, "test description too long")
+	task.Description = strings.Repeat("a", 10001)
+	if err := task.Validate(); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestSearchTaskByDescription(t *testing.T) {
+	task := newTaskOrFatal(t, "test search by description")
+	task.Description = "a *unique* description to search"
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	tasks, _, err := SearchTask("", "unique\\* description", false, true, 1, 10)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if len(tasks) != 1 || tasks[0].SID != task.SID {
+		t.Errorf("expected the task %v, got %v", task.SID, tasks)
+	}
+}
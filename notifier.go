@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"time"
+)
+
+// Notifier deliver a reminder of a task.
+type Notifier interface {
+	Notify(r *Reminder, t *Task) error
+}
+
+// notifiers is the configured notifiers by name, a reminder can only use one
+// of them.
+var notifiers = map[string]Notifier{"log": LogNotifier{}}
+
+// LogNotifier write the reminders to the standard logger.
+type LogNotifier struct{}
+
+// Notify log the reminder.
+func (LogNotifier) Notify(r *Reminder, t *Task) error {
+	log.Printf("reminder %v: task %v %q is due at %v", r.SID, t.SID, t.Title, t.DueDate)
+	return nil
+}
+
+// WebhookNotifier post the reminders as JSON to an URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify post the reminder, any status other than 2xx is a failure.
+func (n *WebhookNotifier) Notify(r *Reminder, t *Task) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"reminder":  r.SID,
+		"task":      t.SID,
+		"title":     t.Title,
+		"due_at":    t.DueDate,
+		"remind_at": r.RemindAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %v answered %v", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier send the reminders by mail.
+type SMTPNotifier struct {
+	Addr string
+	From string
+	To   []string
+	Auth smtp.Auth
+}
+
+// Notify send the reminder mail.
+func (n *SMTPNotifier) Notify(r *Reminder, t *Task) error {
+	// The title is encoded in the subject so it can't add headers.
+	subject := mime.QEncoding.Encode("utf-8", "Reminder: "+t.Title)
+	msg := fmt.Sprintf("From: %v\r\nSubject: %v\r\n\r\nThe task %q is due at %v.\r\n", n.From, subject, t.Title, t.DueDate)
+	return smtp.SendMail(n.Addr, n.Auth, n.From, n.To, []byte(msg))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := new(strings.Builder)
+		bufio.NewReader(r.Body).WriteTo(b)
+		body = b.String()
+	}))
+	defer ts.Close()
+
+	n := &WebhookNotifier{URL: ts.URL}
+	if err := n.Notify(&Reminder{SID: "r1"}, &Task{SID: "t1", Title: "webhook task"}); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if !strings.Contains(body, "webhook task") {
+		t.Errorf("expected the task in the body, got %v", body)
+	}
+}
+
+func TestWebhookNotifierFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	n := &WebhookNotifier{URL: ts.URL}
+	if err := n.Notify(&Reminder{}, &Task{}); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+// serveSMTP answer a single mail on a local listener and return its data.
+func serveSMTP(t *testing.T, l net.Listener, data chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		t.Errorf("unexpected error (%v)", err)
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost")
+
+	var msg []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			for {
+				l, _ := r.ReadString('\n')
+				if l == ".\r\n" {
+					break
+				}
+				msg = append(msg, l)
+			}
+			reply("250 ok")
+			data <- strings.Join(msg, "")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	defer l.Close()
+
+	data := make(chan string, 1)
+	go serveSMTP(t, l, data)
+
+	n := &SMTPNotifier{Addr: l.Addr().String(), From: "task@localhost", To: []string{"user@localhost"}}
+	if err := n.Notify(&Reminder{}, &Task{Title: "smtp task"}); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if msg := <-data; !strings.Contains(msg, "smtp task") {
+		t.Errorf("expected the task in the mail, got %v", msg)
+	}
+}
+
+func TestSMTPNotifierHeaderInjection(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	defer l.Close()
+
+	data := make(chan string, 1)
+	go serveSMTP(t, l, data)
+
+	n := &SMTPNotifier{Addr: l.Addr().String(), From: "task@localhost", To: []string{"user@localhost"}}
+	if err := n.Notify(&Reminder{}, &Task{Title: "smtp task\r\nBcc: other@localhost"}); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if msg := <-data; strings.Contains(msg, "\r\nBcc:") {
+		t.Errorf("expected the title in the subject only, got %v", msg)
+	}
+}
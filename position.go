@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrInvalidTarget is returned when a task is moved next to an invalid task
+// ID or next to itself.
+var ErrInvalidTarget = errors.New("the target must be the ID of another task")
+
+// positionGap is the space left between two task positions.
+const positionGap = 1024.0
+
+// minPositionGap is the smallest space before renumbering the positions.
+const minPositionGap = 1e-6
+
+// priorities is the ordered list of task priorities.
+var priorities = []string{"none", "low", "medium", "high", "urgent"}
+
+// sortKeys is the mongoDB field of each sortable attribute.
+var sortKeys = map[string]string{
+	"title":      "title",
+	"priority":   "priorityRank",
+	"position":   "position",
+	"due_at":     "dueAt",
+	"created_at": "createdAt",
+}
+
+// priorityRank return the sortable rank of a priority.
+func priorityRank(priority string) int {
+	for i, p := range priorities {
+		if p == priority {
+			return i
+		}
+	}
+	return 0
+}
+
+// sortFields convert the JSON:API sort fields to mongoDB sort fields. The
+// custom fields are sorted with custom.<name>.
+func sortFields(sort []string) ([]string, error) {
+	if len(sort) == 0 {
+		return []string{"title", "_id"}, nil
+	}
+
+	var fields []string
+	for _, s := range sort {
+		desc := strings.HasPrefix(s, "-")
+		name := strings.TrimPrefix(s, "-")
+		key, ok := sortKeys[name]
+		if f := strings.TrimPrefix(name, "custom."); f != name && fieldName.MatchString(f) {
+			key, ok = name, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown sort field %v", s)
+		}
+		if desc {
+			key = "-" + key
+		}
+		fields = append(fields, key)
+	}
+	return append(fields, "_id"), nil
+}
+
+// nextPosition return the position after the last task of the list.
+func nextPosition(c *mgo.Collection, list string) (float64, error) {
+	last := &Task{}
+	err := c.Find(bson.M{"list": listKey(list)}).Sort("-position").Select(bson.M{"position": 1}).One(last)
+	if err == mgo.ErrNotFound {
+		return positionGap, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return last.Position + positionGap, nil
+}
+
+// neighbour return the closest task of the list before or after a position,
+// nil if there is none.
+func neighbour(c *mgo.Collection, list string, position float64, after bool, exclude bson.ObjectId) (*Task, error) {
+	op, sort := "$lt", "-position"
+	if after {
+		op, sort = "$gt", "position"
+	}
+
+	t := &Task{}
+	err := c.Find(bson.M{"list": listKey(list), "position": bson.M{op: position}, "_id": bson.M{"$ne": exclude}}).Sort(sort).One(t)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// renumberPositions spread again the positions of a list with the default gap,
+// as done by the actor.
+func renumberPositions(c *mgo.Collection, actor string, list string) error {
+	var t Task
+	iter := c.Find(bson.M{"list": listKey(list)}).Sort("position", "_id").Select(bson.M{"_id": 1}).Iter()
+	for i := 1; iter.Next(&t); i++ {
+		if err := auditedUpdate(c, actor, bson.M{"_id": t.ID}, bson.M{"$set": bson.M{"position": float64(i) * positionGap}}); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	return iter.Close()
+}
+
+// MoveTask move a task right before or right after another task, the task
+// joins the list of the other task. mgo.ErrNotFound is returned when one of
+// the tasks or the list joined doesn't exist or the other task isn't visible
+// to the user, ErrForbidden when the user can't edit the list
+// joined and ErrTitleTaken when the list have a task with the same title.
+// The move is recorded in the audit as done by the actor.
+func MoveTask(tenant string, user string, actor string, id string, target string, after bool) (*Task, error) {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) || !bson.IsObjectIdHex(target) || id == target {
+		return nil, ErrInvalidTarget
+	}
+
+	m := &Task{}
+	if err := c.FindId(bson.ObjectIdHex(id)).Select(bson.M{"list": 1, "title": 1, "previousOccurrence": 1}).One(m); err == mgo.ErrNotFound {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("can't find the task %v (%v)", id, err)
+	}
+
+	for renumbered := false; ; renumbered = true {
+		t := &Task{}
+		if err := c.FindId(bson.ObjectIdHex(target)).One(t); err == mgo.ErrNotFound {
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("can't find the task %v (%v)", target, err)
+		}
+
+		// The target task must be visible to the user.
+		role, err := taskRole(c.Database, t, user)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			return nil, mgo.ErrNotFound
+		}
+
+		// Joining another list needs to edit it.
+		if t.ListID != "" && t.ListID != m.ListID {
+			role, err := ListRole(tenant, user, t.ListID)
+			if err != nil {
+				return nil, err
+			}
+			if !grants(role, RoleEditor) {
+				return nil, ErrForbidden
+			}
+		}
+		if t.ListID != m.ListID && m.PreviousOccurrence == "" {
+			if err := checkTitle(c, m.Title, t.ListID, m.ID); err != nil {
+				return nil, err
+			}
+		}
+
+		n, err := neighbour(c, t.ListID, t.Position, after, bson.ObjectIdHex(id))
+		if err != nil {
+			return nil, err
+		}
+
+		// Take the middle of the free space next to the target.
+		position := t.Position - positionGap
+		if after {
+			position = t.Position + positionGap
+		}
+		if n != nil {
+			position = (t.Position + n.Position) / 2
+		}
+
+		if math.Abs(position-t.Position) < minPositionGap && !renumbered {
+			if err := renumberPositions(c, actor, t.ListID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		set := bson.M{"position": position}
+		update := bson.M{"$set": set}
+		if t.ListID != "" {
+			set["list"] = t.ListID
+		} else {
+			update["$unset"] = bson.M{"list": ""}
+		}
+		if err := auditedUpdate(c, actor, bson.M{"_id": bson.ObjectIdHex(id)}, update); err == mgo.ErrNotFound {
+			return nil, err
+		} else if mgo.IsDup(err) {
+			return nil, ErrTitleTaken
+		} else if err != nil {
+			return nil, fmt.Errorf("can't to move the task (%v)", err)
+		}
+		return SelectTask(tenant, id)
+	}
+}
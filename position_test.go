@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestSortFields(t *testing.T) {
+	sort, err := sortFields([]string{"-priority", "position"})
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	expected := []string{"-priorityRank", "position", "_id"}
+	if !reflect.DeepEqual(sort, expected) {
+		t.Errorf("expected sort %v, got %v", expected, sort)
+	}
+
+	if _, err := sortFields([]string{"unknown"}); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestPriorityRank(t *testing.T) {
+	if priorityRank("urgent") <= priorityRank("low") {
+		t.Errorf("expected urgent to rank above low")
+	}
+}
+
+func TestInvalidPriority(t *testing.T) {
+	task := newTaskOrFatal(t, "test invalid priority")
+	task.Priority = "critical"
+	if err := task.Validate(); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestMoveTask(t *testing.T) {
+	first := createTaskOrFatal(t, "test move task first")
+	second := createTaskOrFatal(t, "test move task second")
+
+	moved, err := MoveTask("", "", "", second.SID, first.SID, false)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if moved.Position >= first.Position {
+		t.Errorf("expected position before %v, got %v", first.Position, moved.Position)
+	}
+
+	moved, err = MoveTask("", "", "", second.SID, first.SID, true)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if moved.Position <= first.Position {
+		t.Errorf("expected position after %v, got %v", first.Position, moved.Position)
+	}
+}
+
+func TestMoveTaskAPIUnknownTarget(t *testing.T) {
+	task := createTaskOrFatal(t, "test move task unknown target")
+
+	m := mux.NewRouter()
+	m.HandleFunc("/task/{sid}/move", MoveTaskAPI)
+
+	for target, code := range map[string]int{
+		bson.NewObjectId().Hex(): http.StatusNotFound,
+		"not-an-id":              http.StatusBadRequest,
+		task.SID:                 http.StatusBadRequest,
+	} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/task/"+task.SID+"/move?after="+target, nil)
+		m.ServeHTTP(rr, req)
+
+		// Test status code.
+		if rr.Code != code {
+			t.Errorf("%v : expected %v, got %v (%v)", target, code, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestMoveTaskToViewedList(t *testing.T) {
+	alice := createUserOrFatal(t, "alice move viewed list")
+	bob := createUserOrFatal(t, "bob move viewed list")
+
+	list, err := NewList("test move viewed list")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	list.CreatedByID = alice.SID
+	if err := list.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := ShareList("", alice.SID, list.SID, &Share{UserID: bob.SID, Role: RoleViewer}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	target := newTaskOrFatal(t, "test move viewed list target")
+	target.ListID = list.SID
+	if err := target.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	task := newTaskOrFatal(t, "test move viewed list task")
+	task.CreatedByID = bob.SID
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if _, err := MoveTask("", bob.SID, bob.SID, task.SID, target.SID, true); err != ErrForbidden {
+		t.Errorf("expected error %v, got %v", ErrForbidden, err)
+	}
+}
+
+func TestMoveTaskNextToHiddenTask(t *testing.T) {
+	alice := createUserOrFatal(t, "alice move hidden target")
+	bob := createUserOrFatal(t, "bob move hidden target")
+
+	target := newTaskOrFatal(t, "test move hidden target")
+	target.CreatedByID = alice.SID
+	if err := target.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	task := newTaskOrFatal(t, "test move hidden target task")
+	task.CreatedByID = bob.SID
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if _, err := MoveTask("", bob.SID, bob.SID, task.SID, target.SID, true); err != mgo.ErrNotFound {
+		t.Errorf("expected error %v, got %v", mgo.ErrNotFound, err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// resourceTypes references the models exposed by the API by JSON:API type.
+var resourceTypes = map[string]interface{}{
+	"task": Task{},
+}
+
+// Params is the JSON:API query parameters of a read request.
+type Params struct {
+	// Fields is the sparse fieldsets by resource type.
+	Fields map[string][]string
+	// Include is the list of relationships to include in the document.
+	Include []string
+	// RenderHTML ask for the HTML rendering of the Markdown attributes.
+	RenderHTML bool
+}
+
+// ParseParams read the `fields[type]` and `include` query parameters.
+// The relations argument is the relationships the endpoint is able to include.
+func ParseParams(r *http.Request, relations []string) (*Params, error) {
+	p := &Params{Fields: map[string][]string{}}
+	v := r.URL.Query()
+
+	for k := range v {
+		if !strings.HasPrefix(k, "fields[") || !strings.HasSuffix(k, "]") {
+			continue
+		}
+		typ := k[len("fields[") : len(k)-1]
+		model, ok := resourceTypes[typ]
+		if !ok {
+			return nil, fmt.Errorf("unknown resource type %v", typ)
+		}
+
+		members := fieldMembers(model)
+		fields := []string{}
+		for _, f := range splitList(v.Get(k)) {
+			if _, ok := members[f]; !ok {
+				return nil, fmt.Errorf("unknown field %v for type %v", f, typ)
+			}
+			fields = append(fields, f)
+		}
+		p.Fields[typ] = fields
+	}
+
+	for _, i := range splitList(v.Get("include")) {
+		if !contains(relations, i) {
+			return nil, fmt.Errorf("relationship %v can't be included", i)
+		}
+		p.Include = append(p.Include, i)
+	}
+
+	switch render := v.Get("render"); render {
+	case "":
+	case "html":
+		p.RenderHTML = true
+	default:
+		return nil, fmt.Errorf("unknown render format %v", render)
+	}
+
+	return p, nil
+}
+
+// Projected return the fields of a type to read from the database, nil for
+// every field. The included relationships are read even when they are out of
+// the sparse fieldset so they can be loaded, writePayload leaves them out of
+// the document.
+func (p *Params) Projected(typ string) []string {
+	fields, ok := p.Fields[typ]
+	if !ok {
+		return nil
+	}
+	return append(append([]string{}, fields...), p.Include...)
+}
+
+// contains check if the list have the value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList split a comma separated parameter value.
+func splitList(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// fieldMembers return the JSON:API attributes and relationships of a model
+// with the name of the mongoDB fields storing them. A computed member lists
+// the fields it is derived from in its `compute` tag.
+func fieldMembers(model interface{}) map[string][]string {
+	members := map[string][]string{}
+	t := reflect.TypeOf(model)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("jsonapi"), ",")
+		if len(tag) < 2 || (tag[0] != "attr" && tag[0] != "relation") {
+			continue
+		}
+		key := strings.Split(f.Tag.Get("bson"), ",")[0]
+		if key == "-" {
+			members[tag[1]] = splitList(f.Tag.Get("compute"))
+		} else {
+			members[tag[1]] = []string{key}
+		}
+	}
+	return members
+}
+
+// Projection build the mongoDB projection for a sparse fieldset,
+// a nil projection select the whole document.
+func Projection(model interface{}, fields []string) bson.M {
+	if fields == nil {
+		return nil
+	}
+
+	members := fieldMembers(model)
+	p := bson.M{"_id": 1, "sid": 1}
+	for _, f := range fields {
+		for _, key := range members[f] {
+			p[key] = 1
+		}
+	}
+	return p
+}
+
+// writePayload encode the document produced by marshal and keep only the
+// fields requested by the client.
+func writePayload(w io.Writer, p *Params, marshal func(io.Writer) error) error {
+	if len(p.Fields) == 0 {
+		return marshal(w)
+	}
+
+	var buf bytes.Buffer
+	if err := marshal(&buf); err != nil {
+		return err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		return err
+	}
+
+	switch data := doc["data"].(type) {
+	case map[string]interface{}:
+		sparseNode(data, p.Fields)
+	case []interface{}:
+		for _, n := range data {
+			sparseNode(n.(map[string]interface{}), p.Fields)
+		}
+	}
+	if included, ok := doc["included"].([]interface{}); ok {
+		for _, n := range included {
+			sparseNode(n.(map[string]interface{}), p.Fields)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(doc)
+}
+
+// sparseNode remove the attributes and relationships out of the fieldset.
+func sparseNode(node map[string]interface{}, fields map[string][]string) {
+	typ, _ := node["type"].(string)
+	fs, ok := fields[typ]
+	if !ok {
+		return
+	}
+
+	keep := map[string]bool{}
+	for _, f := range fs {
+		keep[f] = true
+	}
+
+	for _, member := range []string{"attributes", "relationships"} {
+		m, ok := node[member].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k := range m {
+			if !keep[k] {
+				delete(m, k)
+			}
+		}
+		if len(m) == 0 {
+			delete(node, member)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseParams(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/task/?fields[task]=title,done", strings.NewReader(""))
+	p, err := ParseParams(req, nil)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if len(p.Fields["task"]) != 2 {
+		t.Errorf("expected 2 fields, got %v", p.Fields["task"])
+	}
+}
+
+func TestParseParamsWithUnknownField(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/task/?fields[task]=unknown", strings.NewReader(""))
+	if _, err := ParseParams(req, nil); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestParseParamsWithUnknownInclude(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/task/?include=unknown", strings.NewReader(""))
+	if _, err := ParseParams(req, nil); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestProjection(t *testing.T) {
+	if p := Projection(Task{}, nil); p != nil {
+		t.Errorf("expected a nil projection, got %v", p)
+	}
+
+	p := Projection(Task{}, []string{"title"})
+	if p["title"] != 1 || p["done"] != nil {
+		t.Errorf("expected title only projection, got %v", p)
+	}
+}
+
+func TestParamsProjectedWithInclude(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/task/?fields[task]=title&include=tags", strings.NewReader(""))
+	p, err := ParseParams(req, []string{"tags"})
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if f := p.Projected("task"); len(f) != 2 || f[1] != "tags" {
+		t.Errorf("expected the title and tags fields, got %v", f)
+	}
+	if f := p.Projected("list"); f != nil {
+		t.Errorf("expected every field, got %v", f)
+	}
+	if pr := Projection(Task{}, p.Projected("task")); pr["tags"] != 1 {
+		t.Errorf("expected the tags in the projection, got %v", pr)
+	}
+}
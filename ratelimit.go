@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/jsonapi"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Limit is a token bucket: Burst requests are allowed at once and the bucket
+// is refilled in Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit read a limit of the form 100/m, the period is s, m or h.
+func ParseLimit(value string) (Limit, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("limit %q must be requests/period", value)
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("limit %q must have a positive number of requests", value)
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	period, ok := periods[parts[1]]
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must have a period s, m or h", value)
+	}
+	return Limit{Burst: n, Period: period}, nil
+}
+
+// rate return the tokens added to the bucket by second.
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	Tokens    float64   `bson:"tokens"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// LimitResult is the outcome of taking a token.
+type LimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the delay until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the delay until the next token when not allowed.
+	RetryAfter time.Duration
+}
+
+// take refill the bucket up to now then take a token if one is left.
+func (b *bucket) take(l Limit, now time.Time) LimitResult {
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = float64(l.Burst)
+	} else if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = math.Min(float64(l.Burst), b.Tokens+elapsed.Seconds()*l.rate())
+	}
+	b.UpdatedAt = now
+
+	res := LimitResult{Limit: l.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.Tokens) / l.rate())
+	}
+	res.Remaining = int(b.Tokens)
+	res.Reset = seconds((float64(l.Burst) - b.Tokens) / l.rate())
+	return res
+}
+
+// seconds convert a number of seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// LimitStore keep the token buckets by client key.
+type LimitStore interface {
+	// Take take a token from the bucket of the key.
+	Take(key string, l Limit, now time.Time) (LimitResult, error)
+}
+
+// MemoryLimitStore keep the buckets in memory, the limits are per instance.
+type MemoryLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// NewMemoryLimitStore create an empty memory store.
+func NewMemoryLimitStore() *MemoryLimitStore {
+	return &MemoryLimitStore{buckets: map[string]*bucket{}}
+}
+
+// maxLimitPeriod is the longest period of the limits, a bucket unused for the
+// period is full.
+const maxLimitPeriod = time.Hour
+
+// Take take a token from the bucket of the key, the full buckets are removed
+// every minute.
+func (ms *MemoryLimitStore) Take(key string, l Limit, now time.Time) (LimitResult, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if now.Sub(ms.swept) > time.Minute {
+		for k, b := range ms.buckets {
+			if now.Sub(b.UpdatedAt) > maxLimitPeriod {
+				delete(ms.buckets, k)
+			}
+		}
+		ms.swept = now
+	}
+
+	b, ok := ms.buckets[key]
+	if !ok {
+		b = &bucket{}
+		ms.buckets[key] = b
+	}
+	return b.take(l, now), nil
+}
+
+// MongoLimitStore keep the buckets in the default database, the limits are
+// shared by the instances using the same database.
+type MongoLimitStore struct {
+	Collection string
+	index      sync.Once
+}
+
+// maxLimitRetries is the number of attempts to update a bucket changed by
+// another instance.
+const maxLimitRetries = 5
+
+// Take take a token from the bucket of the key. The bucket is updated only if
+// no other request changed it since it was read.
+func (ms *MongoLimitStore) Take(key string, l Limit, now time.Time) (LimitResult, error) {
+	s, c, err := getCollection("", ms.Collection)
+	if err != nil {
+		return LimitResult{}, err
+	}
+	defer s.Close()
+
+	// Expire the buckets once full.
+	ms.index.Do(func() {
+		c.EnsureIndex(mgo.Index{Key: []string{"expiresAt"}, ExpireAfter: time.Second})
+	})
+
+	// Mongodb keep the dates to the millisecond.
+	now = now.Truncate(time.Millisecond)
+	for i := 0; i < maxLimitRetries; i++ {
+		b := &bucket{}
+		err := c.FindId(key).One(b)
+		if err != nil && err != mgo.ErrNotFound {
+			return LimitResult{}, fmt.Errorf("can't to read the rate limit %v (%v)", key, err)
+		}
+		previous := b.UpdatedAt
+
+		res := b.take(l, now)
+		doc := bson.M{"tokens": b.Tokens, "updatedAt": b.UpdatedAt, "expiresAt": now.Add(res.Reset)}
+		if err == mgo.ErrNotFound {
+			doc["_id"] = key
+			err = c.Insert(doc)
+			if mgo.IsDup(err) {
+				continue
+			}
+		} else {
+			err = c.Update(bson.M{"_id": key, "updatedAt": previous}, bson.M{"$set": doc})
+			if err == mgo.ErrNotFound {
+				continue
+			}
+		}
+		if err != nil {
+			return LimitResult{}, fmt.Errorf("can't to update the rate limit %v (%v)", key, err)
+		}
+		return res, nil
+	}
+	return LimitResult{}, fmt.Errorf("can't to update the rate limit %v (too many concurrent requests)", key)
+}
+
+// RateLimiter limit the requests of each client, the reads (GET and HEAD) and
+// the writes have their own limits. The IP limit applies to every request of
+// an IP, before the authentication.
+type RateLimiter struct {
+	Store LimitStore
+	Read  Limit
+	Write Limit
+	IP    Limit
+}
+
+// clientKey return the key of the caller: its API key, its user or its IP.
+func clientKey(r *http.Request) string {
+	if id := RequestIdentity(r); id != nil {
+		if id.KeyID != "" {
+			return "key:" + id.KeyID
+		}
+		if id.UserID != "" {
+			return "user:" + id.Tenant + ":" + id.UserID
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP return the IP of the caller.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// Middleware reject the requests over the limit of the client with a 429.
+// The RateLimit-* headers tell the clients their remaining requests. When the
+// store fails, the requests are allowed.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class, limit := "write", rl.Write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			class, limit = "read", rl.Read
+		}
+
+		if rl.allow(w, class, class+":"+clientKey(r), limit) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// IPMiddleware reject the requests over the IP limit of the client IP with a
+// 429, whether they are authenticated or not. It is placed in front of the
+// authentication, the unauthenticated requests never reach Middleware.
+func (rl *RateLimiter) IPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rl.allow(w, "IP", "all:ip:"+clientIP(r), rl.IP) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// allow take a token of the key and set the RateLimit-* headers, or write the
+// 429 when none is left. When the store fails, the request is allowed.
+func (rl *RateLimiter) allow(w http.ResponseWriter, class string, key string, limit Limit) bool {
+	res, err := rl.Store.Take(key, limit, time.Now())
+	if err != nil {
+		log.Printf("rate limit: %v", err)
+		return true
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	if !res.Allowed {
+		retry := ceilSeconds(res.RetryAfter)
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		w.Header().Set("Content-Type", jsonapi.MediaType)
+		writeError(w, http.StatusTooManyRequests, "Rate Limit Error", fmt.Sprintf("too many %v requests, retry in %v seconds", class, retry))
+		return false
+	}
+	return true
+}
+
+// ceilSeconds return a duration in whole seconds, rounded up.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	l, err := ParseLimit("100/m")
+	if err != nil || l.Burst != 100 || l.Period != time.Minute {
+		t.Errorf("expected 100 requests by minute, got %v (%v)", l, err)
+	}
+	for _, value := range []string{"100", "0/s", "ten/s", "10/d"} {
+		if _, err := ParseLimit(value); err == nil {
+			t.Errorf("expected an error for %v", value)
+		}
+	}
+}
+
+func TestMemoryLimitStore(t *testing.T) {
+	ms := NewMemoryLimitStore()
+	l := Limit{Burst: 2, Period: 10 * time.Second}
+	now := time.Now()
+
+	for i, expected := range []bool{true, true, false} {
+		res, err := ms.Take("a", l, now)
+		if err != nil {
+			t.Fatalf("unexpected error : %v", err)
+		}
+		if res.Allowed != expected {
+			t.Errorf("request %v : expected %v, got %v", i, expected, res.Allowed)
+		}
+	}
+
+	// Another client has its own bucket.
+	if res, _ := ms.Take("b", l, now); !res.Allowed || res.Remaining != 1 {
+		t.Errorf("expected the client b to be allowed, got %+v", res)
+	}
+
+	// A token is added every 5 seconds.
+	res, _ := ms.Take("a", l, now.Add(time.Second))
+	if res.Allowed || res.RetryAfter != 4*time.Second {
+		t.Errorf("expected a retry after 4s, got %+v", res)
+	}
+	if res, _ := ms.Take("a", l, now.Add(5*time.Second)); !res.Allowed || res.Reset != 10*time.Second {
+		t.Errorf("expected the request to be allowed with a reset in 10s, got %+v", res)
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	rl := &RateLimiter{Store: NewMemoryLimitStore(), Read: Limit{Burst: 2, Period: time.Minute}, Write: Limit{Burst: 1, Period: time.Minute}}
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		method string
+		user   string
+		code   int
+	}{
+		{http.MethodGet, "alice", http.StatusOK},
+		{http.MethodPost, "alice", http.StatusOK},
+		{http.MethodPost, "alice", http.StatusTooManyRequests},
+		{http.MethodGet, "alice", http.StatusOK},
+		{http.MethodGet, "alice", http.StatusTooManyRequests},
+		{http.MethodPost, "bob", http.StatusOK},
+	} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, "/task/", nil)
+		h.ServeHTTP(rr, WithUser(req, tc.user))
+
+		// Test status code.
+		if rr.Code != tc.code {
+			t.Errorf("%v %v: expected %v, got %v", tc.method, tc.user, tc.code, rr.Code)
+		}
+		if rr.Header().Get("RateLimit-Limit") == "" {
+			t.Errorf("expected the RateLimit-Limit header")
+		}
+		if rr.Code == http.StatusTooManyRequests && rr.Header().Get("Retry-After") == "" {
+			t.Errorf("expected the Retry-After header")
+		}
+	}
+}
+
+func TestRateLimiterIPMiddleware(t *testing.T) {
+	rl := &RateLimiter{Store: NewMemoryLimitStore(), IP: Limit{Burst: 2, Period: time.Minute}}
+	h := rl.IPMiddleware(RequireAuth(&StaticKey{Key: "secret"}, http.NotFoundHandler()))
+
+	for i, tc := range []struct {
+		addr string
+		code int
+	}{
+		{"10.0.0.1:1234", http.StatusUnauthorized},
+		{"10.0.0.1:1235", http.StatusUnauthorized},
+		{"10.0.0.1:1236", http.StatusTooManyRequests},
+		{"10.0.0.2:1234", http.StatusUnauthorized},
+	} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/task/", nil)
+		req.RemoteAddr = tc.addr
+		h.ServeHTTP(rr, req)
+
+		// Test status code.
+		if rr.Code != tc.code {
+			t.Errorf("request %v from %v: expected %v, got %v", i, tc.addr, tc.code, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// maxOccurrences is the most occurrences returned by a preview.
+const maxOccurrences = 100
+
+// parseRecurrence read an iCalendar RRULE starting at the given time.
+func parseRecurrence(rule string, start time.Time) (*rrule.RRule, error) {
+	opt, err := rrule.StrToROption(strings.TrimPrefix(rule, "RRULE:"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence %v (%v)", rule, err)
+	}
+	opt.Dtstart = start
+	return rrule.NewRRule(*opt)
+}
+
+// validateRecurrence reject a recurrence which is not a RRULE.
+func validateRecurrence(fl validator.FieldLevel) bool {
+	_, err := parseRecurrence(fl.Field().String(), time.Now())
+	return err == nil
+}
+
+// startRecurrence set the start of the series, the first due date or now.
+func (t *Task) startRecurrence() {
+	if t.Recurrence == "" {
+		t.RecurrenceStart = nil
+		return
+	}
+	if t.RecurrenceStart != nil {
+		return
+	}
+	start := time.Now()
+	if t.DueAt != nil {
+		start = *t.DueAt
+	}
+	t.RecurrenceStart = &start
+}
+
+// Occurrences return the next n due dates of a recurring task.
+func (t *Task) Occurrences(n int) ([]time.Time, error) {
+	if t.Recurrence == "" || t.RecurrenceStart == nil {
+		return nil, fmt.Errorf("task %v is not recurring", t.SID)
+	}
+	if n > maxOccurrences {
+		n = maxOccurrences
+	}
+
+	rule, err := parseRecurrence(t.Recurrence, *t.RecurrenceStart)
+	if err != nil {
+		return nil, err
+	}
+
+	from := time.Now()
+	if t.DueAt != nil {
+		from = *t.DueAt
+	}
+
+	occurrences := []time.Time{}
+	for len(occurrences) < n {
+		next := rule.After(from, false)
+		if next.IsZero() {
+			break
+		}
+		occurrences = append(occurrences, next)
+		from = next
+	}
+	return occurrences, nil
+}
+
+// scheduleNext create the next occurrence of a done recurring task, with the
+// same title, list, parent and tags, and record it in the audit as created by
+// the actor. A task schedules its next occurrence only once, the occurrence
+// is checked like the tasks created by Save but keeps the title of the
+// series. A failure keeps the task done with its next occurrence pending.
+func scheduleNext(tenant string, c *mgo.Collection, actor string, id bson.ObjectId) error {
+	t := &Task{}
+	if err := c.FindId(id).One(t); err != nil {
+		return err
+	}
+	if t.Recurrence == "" || t.NextOccurrence != "" {
+		return setNextOccurrence(c, actor, id, t.NextOccurrence)
+	}
+
+	next, err := t.Occurrences(1)
+	if err != nil {
+		return err
+	}
+	if len(next) == 0 {
+		return setNextOccurrence(c, actor, id, "")
+	}
+
+	n := &Task{
+		Title:              t.Title,
+		Description:        t.Description,
+		Priority:           t.Priority,
+		PriorityRank:       t.PriorityRank,
+		EstimateMinutes:    t.EstimateMinutes,
+		Custom:             t.Custom,
+		CreatedByID:        t.CreatedByID,
+		AssigneeID:         t.AssigneeID,
+		Shares:             t.Shares,
+		TagIDs:             t.TagIDs,
+		ListID:             t.ListID,
+		ParentID:           t.ParentID,
+		Recurrence:         t.Recurrence,
+		RecurrenceStart:    t.RecurrenceStart,
+		PreviousOccurrence: t.SID,
+		Status:             workflow.Initial,
+		DueAt:              &next[0],
+		CreatedAt:          time.Now(),
+	}
+	n.ID = bson.NewObjectId()
+	n.SID = n.ID.Hex()
+
+	// The next occurrence start with the checklist unchecked.
+	for _, i := range t.Checklist {
+		n.Checklist = append(n.Checklist, &ChecklistItem{ID: bson.NewObjectId().Hex(), Text: i.Text, Checked: new(bool)})
+	}
+	if err := checkNewTask(tenant, c, n); err != nil {
+		return err
+	}
+	if n.Position, err = nextPosition(c, n.ListID); err != nil {
+		return err
+	}
+
+	if err := c.Insert(n); mgo.IsDup(err) {
+		return ErrTitleTaken
+	} else if err != nil {
+		return fmt.Errorf("can't to persist the next occurrence (%v)", err)
+	}
+	if err := auditTask(c, AuditCreate, actor, nil, n.ID); err != nil {
+		return err
+	}
+	return setNextOccurrence(c, actor, id, n.SID)
+}
+
+// setNextOccurrence link a done recurring task to its next occurrence, if
+// any, and clear the pending occurrence.
+func setNextOccurrence(c *mgo.Collection, actor string, id bson.ObjectId, next string) error {
+	update := bson.M{"$unset": bson.M{"nextPending": ""}}
+	if next != "" {
+		update["$set"] = bson.M{"nextOccurrence": next}
+	}
+	return auditedUpdate(c, actor, bson.M{"_id": id}, update)
+}
+
+// schedulePending retry the pending next occurrences of the done tasks of a
+// tenant, as done by the users who completed them.
+func schedulePending(tenant string) error {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var tasks []*Task
+	if err := c.Find(bson.M{"nextPending": true, "done": true}).Select(bson.M{"_id": 1, "sid": 1, "updatedBy": 1}).All(&tasks); err != nil {
+		return err
+	}
+	var first error
+	for _, t := range tasks {
+		if err := scheduleNext(tenant, c, t.UpdatedByID, t.ID); err != nil && first == nil {
+			first = fmt.Errorf("can't to schedule the next occurrence of %v (%v)", t.SID, err)
+		}
+	}
+	return first
+}
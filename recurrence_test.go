@@ -0,0 +1,171 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestInvalidRecurrence(t *testing.T) {
+	task := newTaskOrFatal(t, "test invalid recurrence")
+	task.Recurrence = "FREQ=SOMETIMES"
+	if err := task.Validate(); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	start := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC) // A tuesday.
+	task := &Task{Recurrence: "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3", DueAt: &start}
+	task.startRecurrence()
+
+	occurrences, err := task.Occurrences(5)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	// A start off the rule is not counted.
+	if len(occurrences) != 3 {
+		t.Fatalf("expected 3 occurrences, got %v", occurrences)
+	}
+	if occurrences[0].Weekday() != time.Wednesday || occurrences[1].Weekday() != time.Monday {
+		t.Errorf("expected wednesday then monday, got %v", occurrences)
+	}
+}
+
+func TestDoneRecurringTaskCreateNext(t *testing.T) {
+	task := newTaskOrFatal(t, "test recurring task")
+	task.Recurrence = "FREQ=DAILY;COUNT=2"
+	task.DueDate = "2030-01-01T09:00:00Z"
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	task.Status = "done"
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	tasks, n, err := SearchTask("", `^test recurring task$`, false, false, 1, 10)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if n != 1 || tasks[0].DueDate != "2030-01-02T09:00:00Z" {
+		t.Fatalf("expected the next occurrence on 2030-01-02, got %v", tasks)
+	}
+	if u := selectTaskOrFatal(t, task.SID); u.NextOccurrence != tasks[0].SID || u.NextPending {
+		t.Errorf("expected the next occurrence %v, got %v (pending %v)", tasks[0].SID, u.NextOccurrence, u.NextPending)
+	}
+}
+
+func TestDoneRecurringTaskPartialUpdate(t *testing.T) {
+	task := newTaskOrFatal(t, "test recurring task partial update")
+	task.Recurrence = "FREQ=DAILY;COUNT=3"
+	task.DueDate = "2030-01-01T09:00:00Z"
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	patchTaskOrFatal(t, task.SID, `{"title": "test recurring task partial update", "done": true}`)
+
+	if u := selectTaskOrFatal(t, task.SID); u.Recurrence != task.Recurrence {
+		t.Errorf("expected the recurrence to be kept, got '%v'", u.Recurrence)
+	}
+	_, n, err := SearchTask("", `^test recurring task partial update$`, false, false, 1, 10)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if n != 1 {
+		t.Errorf("expected the next occurrence, got %v tasks", n)
+	}
+}
+
+func TestDoneRecurringTaskTakenTitle(t *testing.T) {
+	task := newTaskOrFatal(t, "test recurring task taken")
+	task.Recurrence = "FREQ=DAILY;COUNT=3"
+	task.DueDate = "2030-01-01T09:00:00Z"
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	// The occurrences of the series share the title.
+	task.Status = "done"
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	next := selectTaskOrFatal(t, selectTaskOrFatal(t, task.SID).NextOccurrence)
+	next.Status = "done"
+	if err := next.Update(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	_, n, err := SearchTask("", `^test recurring task taken$`, false, true, 1, 10)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 occurrences, got %v", n)
+	}
+
+	// The other tasks can't take the title of the series.
+	other := newTaskOrFatal(t, "test recurring task taken")
+	if err := other.Save(""); err != ErrTitleTaken {
+		t.Errorf("expected error %v, got %v", ErrTitleTaken, err)
+	}
+}
+
+func TestSchedulerPendingOccurrence(t *testing.T) {
+	task := newTaskOrFatal(t, "test recurring task pending")
+	task.Recurrence = "FREQ=DAILY;COUNT=2"
+	task.DueDate = "2030-01-01T09:00:00Z"
+	task.Done = true
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	// A failed schedule leaves the next occurrence pending.
+	s, c, err := getDatabase("")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer s.Close()
+	if err := c.UpdateId(task.ID, bson.M{"$set": bson.M{"nextPending": true}}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := NewScheduler(nil).Tick(time.Now()); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if u := selectTaskOrFatal(t, task.SID); u.NextOccurrence == "" || u.NextPending {
+		t.Errorf("expected the next occurrence to be created, got %v (pending %v)", u.NextOccurrence, u.NextPending)
+	}
+}
+
+func TestRecurringTaskAudit(t *testing.T) {
+	task := newTaskOrFatal(t, "test recurring task audit")
+	task.Recurrence = "FREQ=DAILY;COUNT=3"
+	task.DueDate = "2030-01-01T09:00:00Z"
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	task.Status = "done"
+	task.UpdatedByID = "recurring task actor"
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	tasks, n, err := SearchTask("", `^test recurring task audit \(`, false, false, 1, 10)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected the next occurrence, got %v", tasks)
+	}
+
+	entries, err := TaskHistory("", tasks[0].SID)
+	if err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if len(entries) != 1 || entries[0].Action != AuditCreate || entries[0].UserID != task.UpdatedByID {
+		t.Errorf("expected the creation of the next occurrence by the actor, got %v", entries)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Reminder states.
+const (
+	ReminderPending = "pending"
+	ReminderSent    = "sent"
+	ReminderFailed  = "failed"
+)
+
+// ErrNoDueDate is returned when a relative reminder is set on a task without
+// due date.
+var ErrNoDueDate = errors.New("task have no due date for a relative reminder")
+
+// ErrNotifierNotConfigured is returned when a reminder use a notifier which is
+// not configured, it could never be delivered.
+var ErrNotifierNotConfigured = errors.New("notifier of the reminder is not configured")
+
+// Reminder notify about a task at a fixed time or some minutes before the
+// task is due.
+type Reminder struct {
+	ID     bson.ObjectId `bson:"_id,omitempty"`
+	SID    string        `bson:"sid,omitempty" jsonapi:"primary,reminder"`
+	TaskID string        `bson:"task" jsonapi:"attr,task_id"`
+	// At is the absolute time, a reminder without it is relative to the due date.
+	At       string `bson:"at,omitempty" validate:"omitempty,duedate" jsonapi:"attr,at"`
+	Before   int    `bson:"before" validate:"min=0" jsonapi:"attr,before_minutes"`
+	Notifier string `bson:"notifier" validate:"omitempty,oneof=log webhook smtp" jsonapi:"attr,notifier"`
+	// RemindAt is the time to fire, nil while a relative reminder has no due date.
+	RemindAt    *time.Time `bson:"remindAt,omitempty" jsonapi:"attr,remind_at,iso8601,omitempty"`
+	State       string     `bson:"state" jsonapi:"attr,state"`
+	Attempts    int        `bson:"attempts" jsonapi:"attr,attempts"`
+	LockedUntil time.Time  `bson:"lockedUntil"`
+	SentAt      *time.Time `bson:"sentAt,omitempty" jsonapi:"attr,sent_at,iso8601,omitempty"`
+}
+
+func init() {
+	resourceTypes["reminder"] = Reminder{}
+}
+
+// Validate checks attributes's integrity.
+func (r *Reminder) Validate() error {
+	v := validator.New()
+	v.RegisterValidation("duedate", validateDueDate)
+	err := v.Struct(r)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// schedule set the time to fire from the due time of the task.
+func (r *Reminder) schedule(dueAt *time.Time) error {
+	if r.At != "" {
+		at, err := parseDueTime(r.At)
+		if err != nil {
+			return err
+		}
+		at = at.UTC()
+		r.RemindAt = &at
+		return nil
+	}
+
+	r.RemindAt = nil
+	if dueAt != nil {
+		at := dueAt.Add(-time.Duration(r.Before) * time.Minute)
+		r.RemindAt = &at
+	}
+	return nil
+}
+
+// Save persist a new reminder of the task.
+func (r *Reminder) Save(tenant string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "reminders")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	task, err := SelectTask(tenant, r.TaskID)
+	if err != nil {
+		return err
+	}
+	if !task.ID.Valid() {
+		return fmt.Errorf("unknown task %v", r.TaskID)
+	}
+	if r.At == "" && task.DueAt == nil {
+		return ErrNoDueDate
+	}
+
+	if err := r.schedule(task.DueAt); err != nil {
+		return err
+	}
+	if r.Notifier == "" {
+		r.Notifier = "log"
+	}
+	if _, ok := notifiers[r.Notifier]; !ok {
+		return ErrNotifierNotConfigured
+	}
+	r.ID = bson.NewObjectId()
+	r.SID = r.ID.Hex()
+	r.State = ReminderPending
+	r.Attempts = 0
+
+	if err := c.Insert(r); err != nil {
+		return fmt.Errorf("can't to persist the reminder (%v)", err)
+	}
+	return nil
+}
+
+// SearchReminders return the reminders of a task sorted by time.
+func SearchReminders(tenant string, taskID string) ([]*Reminder, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "reminders")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	reminders := []*Reminder{}
+	if err := c.Find(bson.M{"task": taskID}).Sort("remindAt").All(&reminders); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+	return reminders, nil
+}
+
+// SelectReminder find a reminder by ID.
+func SelectReminder(tenant string, id string) (*Reminder, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "reminders")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, fmt.Errorf("id value is not valid (%v)", id)
+	}
+
+	r := &Reminder{}
+	if err := c.FindId(bson.ObjectIdHex(id)).One(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// DeleteReminder remove a reminder.
+func DeleteReminder(tenant string, id string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "reminders")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return fmt.Errorf("id value is not valid (%v)", id)
+	}
+	return c.RemoveId(bson.ObjectIdHex(id))
+}
+
+// rescheduleReminders move the pending relative reminders of a task after a
+// change of its due date.
+func rescheduleReminders(db *mgo.Database, taskID string, dueAt *time.Time) error {
+	c := db.C("reminders")
+
+	var reminders []*Reminder
+	if err := c.Find(bson.M{"task": taskID, "at": bson.M{"$exists": false}, "state": ReminderPending}).All(&reminders); err != nil {
+		return err
+	}
+	for _, r := range reminders {
+		if err := r.schedule(dueAt); err != nil {
+			return err
+		}
+		update := bson.M{"$set": bson.M{"remindAt": r.RemindAt}}
+		if r.RemindAt == nil {
+			update = bson.M{"$unset": bson.M{"remindAt": ""}}
+		}
+		if err := c.UpdateId(r.ID, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeReminders remove the reminders of deleted tasks.
+func removeReminders(db *mgo.Database, taskIDs []string) error {
+	_, err := db.C("reminders").RemoveAll(bson.M{"task": bson.M{"$in": taskIDs}})
+	return err
+}
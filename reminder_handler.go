@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+	mgo "gopkg.in/mgo.v2"
+)
+
+// CreateReminderAPI add a reminder to the task of the route.
+func CreateReminderAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Save Error"); err != nil {
+		return
+	}
+
+	reminder := new(Reminder)
+	if err := populateModel(r.Body, w, reminder); err != nil {
+		return
+	}
+	reminder.TaskID = mux.Vars(r)["sid"]
+
+	if err := validateModel(reminder, w); err != nil {
+		return
+	}
+
+	// Save the reminder.
+	if err := reminder.Save(RequestTenant(r)); err == ErrNoDueDate || err == ErrNotifierNotConfigured {
+		writeError(w, http.StatusBadRequest, "Save Error", err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, "Save Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, reminder)
+}
+
+// SearchReminderAPI return the reminders of the task of the route.
+func SearchReminderAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleViewer, "Search Error"); err != nil {
+		return
+	}
+
+	reminders, err := SearchReminders(RequestTenant(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, reminders, len(reminders))
+}
+
+// DeleteReminderAPI remove a reminder and return a 204 (no-content) response.
+func DeleteReminderAPI(w http.ResponseWriter, r *http.Request) {
+
+	reminder, err := SelectReminder(RequestTenant(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeAccessError(w, "Delete Error", mgo.ErrNotFound)
+		return
+	}
+	if err := authorizeTask(w, r, reminder.TaskID, RoleEditor, "Delete Error"); err != nil {
+		return
+	}
+
+	if err := DeleteReminder(RequestTenant(r), reminder.SID); err != nil {
+		writeError(w, http.StatusInternalServerError, "Delete Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
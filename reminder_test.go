@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// recordNotifier keep the notified reminders and fail on demand.
+type recordNotifier struct {
+	sent []string
+	err  error
+}
+
+func (n *recordNotifier) Notify(r *Reminder, t *Task) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, r.SID)
+	return nil
+}
+
+func createDueTaskOrFatal(t *testing.T, title string, due time.Time) *Task {
+	task := newTaskOrFatal(t, title)
+	task.DueDate = due.Format(time.RFC3339)
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return task
+}
+
+func TestRelativeReminder(t *testing.T) {
+	due := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	task := createDueTaskOrFatal(t, "test relative reminder", due)
+
+	r := &Reminder{TaskID: task.SID, Before: 60}
+	if err := r.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if !r.RemindAt.Equal(due.Add(-time.Hour)) {
+		t.Errorf("expected reminder at %v, got %v", due.Add(-time.Hour), r.RemindAt)
+	}
+
+	// Move the due date.
+	task.DueDate = due.Add(time.Hour).Format(time.RFC3339)
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	reminders, err := SearchReminders("", task.SID)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(reminders) != 1 || !reminders[0].RemindAt.Equal(due) {
+		t.Errorf("expected reminder at %v, got %v", due, reminders)
+	}
+}
+
+func TestRelativeReminderWithoutDueDate(t *testing.T) {
+	task := createTaskOrFatal(t, "test relative reminder without due date")
+	r := &Reminder{TaskID: task.SID, Before: 60}
+	if err := r.Save(""); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestSchedulerDelivery(t *testing.T) {
+	task := createDueTaskOrFatal(t, "test scheduler delivery", time.Now().Add(time.Hour))
+	n := &recordNotifier{err: errors.New("unavailable")}
+	notifiers["webhook"] = n
+	defer delete(notifiers, "webhook")
+	r := &Reminder{TaskID: task.SID, At: time.Now().Add(-time.Minute).Format(time.RFC3339), Notifier: "webhook"}
+	if err := r.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	// A failed delivery is retried after the lease.
+	s := NewScheduler(map[string]Notifier{"webhook": n})
+	now := time.Now()
+	if err := s.Tick(now); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	n.err = nil
+	if err := s.Tick(now); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if contains(n.sent, r.SID) {
+		t.Errorf("expected no delivery during the lease, got %v", n.sent)
+	}
+
+	if err := s.Tick(now.Add(s.Lease)); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if !contains(n.sent, r.SID) {
+		t.Errorf("expected the reminder %v to be sent, got %v", r.SID, n.sent)
+	}
+
+	reminders, _ := SearchReminders("", task.SID)
+	if len(reminders) != 1 || reminders[0].State != ReminderSent || reminders[0].Attempts != 2 {
+		t.Errorf("expected a reminder sent after 2 attempts, got %v", reminders)
+	}
+}
+
+func TestSchedulerMissingTask(t *testing.T) {
+	task := createDueTaskOrFatal(t, "test scheduler missing task", time.Now().Add(time.Hour))
+	n := &recordNotifier{}
+	notifiers["webhook"] = n
+	defer delete(notifiers, "webhook")
+	r := &Reminder{TaskID: task.SID, At: time.Now().Add(-time.Minute).Format(time.RFC3339), Notifier: "webhook"}
+	if err := r.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	// The task is gone without its reminders.
+	s, c, err := getDatabase("")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer s.Close()
+	if err := c.RemoveId(task.ID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := NewScheduler(map[string]Notifier{"webhook": n}).Tick(time.Now()); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if contains(n.sent, r.SID) {
+		t.Errorf("expected no delivery, got %v", n.sent)
+	}
+	if found, err := SelectReminder("", r.SID); err != nil || found.State != ReminderFailed {
+		t.Errorf("expected a failed reminder, got %v (%v)", found, err)
+	}
+}
+
+func TestCreateReminderAPIWithoutDueDate(t *testing.T) {
+	task := createTaskOrFatal(t, "test relative reminder without due date")
+
+	m := mux.NewRouter()
+	m.HandleFunc("/task/{sid}/reminders", CreateReminderAPI)
+
+	rr := httptest.NewRecorder()
+	body := `{"data": {"type": "reminder", "attributes": {"before_minutes": 60}}}`
+	req, _ := http.NewRequest(http.MethodPost, "/task/"+task.SID+"/reminders", strings.NewReader(body))
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestCreateReminderAPINotConfigured(t *testing.T) {
+	task := createDueTaskOrFatal(t, "test reminder notifier not configured", time.Now().Add(time.Hour))
+
+	m := mux.NewRouter()
+	m.HandleFunc("/task/{sid}/reminders", CreateReminderAPI)
+
+	rr := httptest.NewRecorder()
+	body := `{"data": {"type": "reminder", "attributes": {"before_minutes": 60, "notifier": "smtp"}}}`
+	req, _ := http.NewRequest(http.MethodPost, "/task/"+task.SID+"/reminders", strings.NewReader(body))
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// maxReminderAttempts is the deliveries tried before a reminder fails.
+const maxReminderAttempts = 5
+
+// errNoReminderTask is returned when the task of a reminder doesn't exist
+// anymore, the reminder fails without retry.
+var errNoReminderTask = errors.New("task of the reminder doesn't exist")
+
+// Scheduler fire the pending reminders. A reminder is leased while it is
+// delivered, so a crash during delivery makes it fire again after the lease:
+// delivery is at least once.
+type Scheduler struct {
+	Notifiers map[string]Notifier
+	Interval  time.Duration
+	Lease     time.Duration
+}
+
+// NewScheduler create a scheduler with the notifiers by name.
+func NewScheduler(notifiers map[string]Notifier) *Scheduler {
+	return &Scheduler{Notifiers: notifiers, Interval: 10 * time.Second, Lease: time.Minute}
+}
+
+// Run fire the reminders at each interval until stop is closed.
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Tick(time.Now()); err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick fire all the reminders due at the given time and retry the pending
+// occurrences of the recurring tasks in every tenant, a failing tenant
+// doesn't stop the others.
+func (s *Scheduler) Tick(now time.Time) error {
+	var first error
+	for _, tenant := range TenantNames() {
+		if err := s.tick(tenant, now); err != nil && first == nil {
+			first = err
+		}
+		if err := schedulePending(tenant); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// tick fire the reminders of a tenant due at the given time.
+func (s *Scheduler) tick(tenant string, now time.Time) error {
+	// Get the database connection.
+	session, c, err := getCollection(tenant, "reminders")
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	for {
+		r, err := s.claim(c, now)
+		if err == mgo.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.deliver(tenant, c, r, now)
+	}
+}
+
+// claim lease the next due reminder.
+func (s *Scheduler) claim(c *mgo.Collection, now time.Time) (*Reminder, error) {
+	r := &Reminder{}
+	_, err := c.Find(bson.M{
+		"state":       ReminderPending,
+		"remindAt":    bson.M{"$lte": now},
+		"lockedUntil": bson.M{"$lte": now},
+	}).Sort("remindAt").Apply(mgo.Change{
+		Update:    bson.M{"$set": bson.M{"lockedUntil": now.Add(s.Lease)}, "$inc": bson.M{"attempts": 1}},
+		ReturnNew: true,
+	}, r)
+	return r, err
+}
+
+// deliver notify a leased reminder and record the outcome, a failed delivery
+// is retried later with a growing delay.
+func (s *Scheduler) deliver(tenant string, c *mgo.Collection, r *Reminder, now time.Time) {
+	err := s.notify(tenant, r)
+	if err == nil {
+		// The reminder fires again after the lease when it isn't marked sent.
+		if err := c.UpdateId(r.ID, bson.M{"$set": bson.M{"state": ReminderSent, "sentAt": now}}); err != nil {
+			log.Printf("scheduler: reminder %v sent but can't be marked (%v)", r.SID, err)
+		}
+		return
+	}
+
+	log.Printf("scheduler: reminder %v attempt %v failed (%v)", r.SID, r.Attempts, err)
+	set := bson.M{"lockedUntil": now.Add(time.Duration(r.Attempts) * s.Lease)}
+	if r.Attempts >= maxReminderAttempts || err == errNoReminderTask {
+		set["state"] = ReminderFailed
+	}
+	if err := c.UpdateId(r.ID, bson.M{"$set": set}); err != nil {
+		log.Printf("scheduler: reminder %v can't be released (%v)", r.SID, err)
+	}
+}
+
+// notify send the reminder with its notifier.
+func (s *Scheduler) notify(tenant string, r *Reminder) error {
+	n, ok := s.Notifiers[r.Notifier]
+	if !ok {
+		return fmt.Errorf("notifier %v is not configured", r.Notifier)
+	}
+
+	t, err := SelectTask(tenant, r.TaskID)
+	if err != nil {
+		return err
+	}
+	if !t.ID.Valid() {
+		return errNoReminderTask
+	}
+	return n.Notify(r, t)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+)
+
+// writeShareError write the error of a share request.
+func writeShareError(w http.ResponseWriter, title string, err error) {
+	if err == ErrUnknownUser {
+		writeError(w, http.StatusBadRequest, title, err.Error())
+		return
+	}
+	writeAccessError(w, title, err)
+}
+
+// SearchTaskShareAPI return the shares of a task.
+func SearchTaskShareAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleViewer, "Search Error"); err != nil {
+		return
+	}
+
+	task, err := SelectTask(RequestTenant(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeAccessError(w, "Search Error", err)
+		return
+	}
+	shares := task.Shares
+	if shares == nil {
+		shares = []*Share{}
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, shares, len(shares))
+}
+
+// ShareTaskAPI grant the role of the payload to its user on a task.
+func ShareTaskAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	sh := new(Share)
+	if err := populateModel(r.Body, w, sh); err != nil {
+		return
+	}
+
+	if err := validateModel(sh, w); err != nil {
+		return
+	}
+
+	if err := ShareTask(RequestTenant(r), accessUser(r), RequestUser(r), mux.Vars(r)["sid"], sh); err != nil {
+		writeShareError(w, "Share Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, sh)
+}
+
+// UnshareTaskAPI revoke the role of a user on a task and return a 204
+// (no-content) response.
+func UnshareTaskAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := UnshareTask(RequestTenant(r), accessUser(r), RequestUser(r), mux.Vars(r)["sid"], mux.Vars(r)["user"]); err != nil {
+		writeShareError(w, "Unshare Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SearchListShareAPI return the shares of a list.
+func SearchListShareAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeList(w, r, mux.Vars(r)["list"], RoleViewer, "Search Error"); err != nil {
+		return
+	}
+
+	list, err := SelectList(RequestTenant(r), mux.Vars(r)["list"])
+	if err != nil {
+		writeAccessError(w, "Search Error", err)
+		return
+	}
+	shares := list.Shares
+	if shares == nil {
+		shares = []*Share{}
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, shares, len(shares))
+}
+
+// ShareListAPI grant the role of the payload to its user on a list and its
+// tasks.
+func ShareListAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	sh := new(Share)
+	if err := populateModel(r.Body, w, sh); err != nil {
+		return
+	}
+
+	if err := validateModel(sh, w); err != nil {
+		return
+	}
+
+	if err := ShareList(RequestTenant(r), accessUser(r), mux.Vars(r)["list"], sh); err != nil {
+		writeShareError(w, "Share Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, sh)
+}
+
+// UnshareListAPI revoke the role of a user on a list and return a 204
+// (no-content) response.
+func UnshareListAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := UnshareList(RequestTenant(r), accessUser(r), mux.Vars(r)["list"], mux.Vars(r)["user"]); err != nil {
+		writeShareError(w, "Unshare Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Policies when a parent task is completed while it has open subtasks.
+const (
+	// OpenSubtasksReject refuse to complete the parent.
+	OpenSubtasksReject = "reject"
+	// OpenSubtasksComplete complete the subtasks with the parent.
+	OpenSubtasksComplete = "complete"
+	// OpenSubtasksAllow complete the parent only.
+	OpenSubtasksAllow = "allow"
+)
+
+// openSubtasksPolicy is the behavior when completing a parent task.
+var openSubtasksPolicy = OpenSubtasksReject
+
+// ErrSubtaskCycle is returned when a task would be a subtask of itself.
+var ErrSubtaskCycle = errors.New("task can't be a subtask of itself")
+
+// ErrOpenSubtasks is returned when completing a task with open subtasks is
+// rejected.
+var ErrOpenSubtasks = errors.New("task still have open subtasks")
+
+// maxDepth is the deepest hierarchy of tasks.
+const maxDepth = 100
+
+// Progress is the completion of a set of items.
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+func init() {
+	taskRelations["parent"] = loadTaskParent
+}
+
+// resolveParent checks the parent of the relationship exists and does not
+// create a cycle, then set the stored parent ID.
+func (t *Task) resolveParent(c *mgo.Collection) error {
+	if t.Parent == nil {
+		return nil
+	}
+
+	id := t.Parent.SID
+	for depth := 0; id != ""; depth++ {
+		if id == t.SID {
+			return ErrSubtaskCycle
+		}
+		if depth == maxDepth || !bson.IsObjectIdHex(id) {
+			return fmt.Errorf("invalid parent task %v", t.Parent.SID)
+		}
+
+		p := &Task{}
+		if err := c.FindId(bson.ObjectIdHex(id)).Select(bson.M{"parent": 1}).One(p); err != nil {
+			return fmt.Errorf("unknown parent task %v (%v)", id, err)
+		}
+		id = p.ParentID
+	}
+
+	t.ParentID = t.Parent.SID
+	return nil
+}
+
+// descendants return the ID of every subtask under a task.
+func descendants(c *mgo.Collection, id string) ([]string, error) {
+	var all []string
+	level := []string{id}
+	for depth := 0; len(level) > 0 && depth < maxDepth; depth++ {
+		var children []Task
+		if err := c.Find(bson.M{"parent": bson.M{"$in": level}}).Select(bson.M{"sid": 1}).All(&children); err != nil {
+			return nil, err
+		}
+		level = nil
+		for _, child := range children {
+			level = append(level, child.SID)
+		}
+		all = append(all, level...)
+	}
+	return all, nil
+}
+
+// completeSubtasks apply the open subtasks policy when the task is
+// completed by the actor, the subtasks are only completed with the task if
+// the user can edit each of them.
+func (t *Task) completeSubtasks(c *mgo.Collection, user string, actor string) error {
+	open := bson.M{"parent": t.SID, "done": false, "status": bson.M{"$nin": workflow.Terminal}}
+	n, err := c.Find(open).Count()
+	if err != nil || n == 0 {
+		return err
+	}
+
+	switch openSubtasksPolicy {
+	case OpenSubtasksAllow:
+		return nil
+	case OpenSubtasksComplete:
+		ids, err := descendants(c, t.SID)
+		if err != nil {
+			return err
+		}
+		if err := requireTaskRoles(c, user, ids, RoleEditor); err != nil {
+			return err
+		}
+		now := time.Now()
+		return auditedUpdateAll(c, actor,
+			bson.M{"sid": bson.M{"$in": ids}, "done": false, "status": bson.M{"$nin": workflow.Terminal}},
+			bson.M{"$set": bson.M{"status": workflow.Done, "done": true, "completedAt": now, "updatedAt": now}},
+		)
+	default:
+		return ErrOpenSubtasks
+	}
+}
+
+// LoadSubtaskProgress set the progress of the subtasks visible to the user of
+// the tasks.
+func LoadSubtaskProgress(tenant string, user string, tasks []*Task) error {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var ids []string
+	for _, t := range tasks {
+		ids = append(ids, t.SID)
+	}
+
+	var counts []struct {
+		ID    string `bson:"_id"`
+		Done  int    `bson:"done"`
+		Total int    `bson:"total"`
+	}
+	match, err := visibleTo(c.Database, bson.M{"parent": bson.M{"$in": ids}}, user)
+	if err != nil {
+		return err
+	}
+	// A subtask is closed when it is done or in a terminal status, like the
+	// open subtasks of completeSubtasks.
+	closed := bson.M{"$or": []interface{}{"$done", bson.M{"$in": []interface{}{"$status", workflow.Terminal}}}}
+	pipe := c.Pipe([]bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":   "$parent",
+			"total": bson.M{"$sum": 1},
+			"done":  bson.M{"$sum": bson.M{"$cond": []interface{}{closed, 1, 0}}},
+		}},
+	})
+	if err := pipe.All(&counts); err != nil {
+		return fmt.Errorf("can't to count the subtasks (%v)", err)
+	}
+
+	for _, t := range tasks {
+		for _, n := range counts {
+			if n.ID == t.SID {
+				t.Subtasks = &Progress{Done: n.Done, Total: n.Total}
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteTaskTree remove a task with its subtasks when recursive, otherwise
+// the subtasks are moved under the parent of the removed task.
+func DeleteTaskTree(tenant string, id string, recursive bool) error {
+	return DeleteTaskTreeAs(tenant, "", "", id, recursive)
+}
+
+// DeleteTaskTreeAs remove a task visible to the user like DeleteTaskTree, the
+// removal is recorded in the audit as done by the actor.
+func DeleteTaskTreeAs(tenant string, user string, actor string, id string, recursive bool) error {
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return fmt.Errorf("id value is not valid (%v)", id)
+	}
+
+	t := &Task{}
+	fields := bson.M{"parent": 1}
+	for k := range accessFields {
+		fields[k] = 1
+	}
+	if err := c.FindId(bson.ObjectIdHex(id)).Select(fields).One(t); err != nil {
+		return err
+	}
+
+	// Only the owners can delete the task.
+	role, err := taskRole(c.Database, t, user)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return mgo.ErrNotFound
+	}
+	if !grants(role, RoleOwner) {
+		return ErrForbidden
+	}
+
+	// The subtasks are only removed with the task if the user can edit
+	// each of them.
+	removed := []string{id}
+	if recursive {
+		ids, err := descendants(c, id)
+		if err != nil {
+			return err
+		}
+		if err := requireTaskRoles(c, user, ids, RoleEditor); err != nil {
+			return err
+		}
+		removed = append(removed, ids...)
+	}
+
+	// The removal is recorded before any change, a task isn't removed
+	// without its audit.
+	var docs []bson.M
+	if err := c.Find(bson.M{"sid": bson.M{"$in": removed}}).All(&docs); err != nil {
+		return fmt.Errorf("can't find the removed tasks (%v)", err)
+	}
+	for _, doc := range docs {
+		if err := recordAudit(c.Database, AuditDelete, actor, doc, nil); err != nil {
+			return err
+		}
+	}
+
+	if recursive {
+		if _, err := c.RemoveAll(bson.M{"sid": bson.M{"$in": removed[1:]}}); err != nil {
+			return fmt.Errorf("can't to remove the subtasks (%v)", err)
+		}
+	} else {
+		update := bson.M{"$set": bson.M{"parent": t.ParentID}}
+		if t.ParentID == "" {
+			update = bson.M{"$unset": bson.M{"parent": ""}}
+		}
+		if err := auditedUpdateAll(c, actor, bson.M{"parent": id}, update); err != nil {
+			return fmt.Errorf("can't to move the subtasks (%v)", err)
+		}
+	}
+
+	if err := removeReminders(c.Database, removed); err != nil {
+		return fmt.Errorf("can't to remove the reminders (%v)", err)
+	}
+
+	if err := archiveComments(c.Database, removed); err != nil {
+		return fmt.Errorf("can't to archive the comments (%v)", err)
+	}
+
+	if err := removeAttachments(tenant, c.Database, bson.M{"task": bson.M{"$in": removed}}); err != nil {
+		return fmt.Errorf("can't to remove the attachments (%v)", err)
+	}
+
+	if err := removeTimeEntries(c.Database, removed); err != nil {
+		return fmt.Errorf("can't to remove the time entries (%v)", err)
+	}
+
+	// Removed tasks don't block anymore.
+	if err := auditedUpdateAll(c, actor, bson.M{"blockedBy": bson.M{"$in": removed}}, bson.M{"$pull": bson.M{"blockedBy": bson.M{"$in": removed}}}); err != nil {
+		return fmt.Errorf("can't to unblock the tasks (%v)", err)
+	}
+
+	return c.RemoveId(t.ID)
+}
+
+// loadTaskParent fill the parent relationship of the tasks when the user can
+// view the parent.
+func loadTaskParent(tenant string, user string, tasks []*Task) error {
+	for _, t := range tasks {
+		if t.ParentID == "" {
+			continue
+		}
+		p, err := SelectTaskAs(tenant, user, t.ParentID, nil)
+		if err != nil {
+			return err
+		}
+		if p.ID.Valid() {
+			t.Parent = p
+		}
+	}
+	return nil
+}
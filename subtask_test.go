@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+)
+
+func createSubtaskOrFatal(t *testing.T, parent *Task, title string) *Task {
+	task := newTaskOrFatal(t, title)
+	task.Parent = parent
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return task
+}
+
+func TestSubtaskCycle(t *testing.T) {
+	parent := createTaskOrFatal(t, "test subtask cycle parent")
+	child := createSubtaskOrFatal(t, parent, "test subtask cycle child")
+
+	parent.Parent = child
+	if err := parent.Update(""); err != ErrSubtaskCycle {
+		t.Errorf("expected error %v, got %v", ErrSubtaskCycle, err)
+	}
+}
+
+func TestSubtaskProgress(t *testing.T) {
+	parent := createTaskOrFatal(t, "test subtask progress parent")
+	createSubtaskOrFatal(t, parent, "test subtask progress open")
+	done := createSubtaskOrFatal(t, parent, "test subtask progress done")
+	done.Status = "done"
+	if err := done.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := LoadSubtaskProgress("", "", []*Task{parent}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if parent.Subtasks == nil || parent.Subtasks.Done != 1 || parent.Subtasks.Total != 2 {
+		t.Errorf("expected 1/2 subtasks done, got %v", parent.Subtasks)
+	}
+}
+
+func TestSubtaskProgressCancelled(t *testing.T) {
+	parent := createTaskOrFatal(t, "test subtask progress cancelled parent")
+	for title, status := range map[string]string{"test subtask progress cancelled": "cancelled", "test subtask progress cancelled done": "done"} {
+		child := createSubtaskOrFatal(t, parent, title)
+		child.Status = status
+		if err := child.Update(""); err != nil {
+			t.Fatalf("unexpected error : %v", err)
+		}
+	}
+
+	if err := LoadSubtaskProgress("", "", []*Task{parent}); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if parent.Subtasks == nil || parent.Subtasks.Done != 2 || parent.Subtasks.Total != 2 {
+		t.Errorf("expected 2/2 subtasks closed, got %v", parent.Subtasks)
+	}
+}
+
+func TestCompleteParentWithOpenSubtasks(t *testing.T) {
+	parent := createTaskOrFatal(t, "test complete parent")
+	child := createSubtaskOrFatal(t, parent, "test complete parent child")
+
+	parent.Status = "done"
+	if err := parent.Update(""); err != ErrOpenSubtasks {
+		t.Errorf("expected error %v, got %v", ErrOpenSubtasks, err)
+	}
+
+	openSubtasksPolicy = OpenSubtasksComplete
+	defer func() { openSubtasksPolicy = OpenSubtasksReject }()
+
+	parent.Done = true
+	parent.Status = ""
+	if err := parent.Update(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if find := selectTaskOrFatal(t, child.SID); !find.Done {
+		t.Errorf("expected a done subtask, got %v", find.Done)
+	}
+}
+
+func TestDeleteTaskReparentSubtasks(t *testing.T) {
+	root := createTaskOrFatal(t, "test delete subtasks root")
+	middle := createSubtaskOrFatal(t, root, "test delete subtasks middle")
+	leaf := createSubtaskOrFatal(t, middle, "test delete subtasks leaf")
+
+	if err := DeleteTask("", middle.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if find := selectTaskOrFatal(t, leaf.SID); find.ParentID != root.SID {
+		t.Errorf("expected parent %v, got %v", root.SID, find.ParentID)
+	}
+
+	if err := DeleteTaskTree("", root.SID, true); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if find := selectTaskOrFatal(t, leaf.SID); find.ID.Valid() {
+		t.Errorf("expected the subtask to be removed, got %v", find.SID)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrTagTaken is returned when another tag has the name.
+var ErrTagTaken = errors.New("tag already exists")
+
+// nameIndexes is the databases having the unique index of the tag names.
+var nameIndexes sync.Map
+
+// Tag is a label put on tasks.
+type Tag struct {
+	ID    bson.ObjectId `bson:"_id,omitempty"`
+	SID   string        `bson:"sid,omitempty" jsonapi:"primary,tag"`
+	Name  string        `bson:"name" validate:"required,max=50" jsonapi:"attr,name"`
+	Color string        `bson:"color,omitempty" validate:"omitempty,hexcolor" jsonapi:"attr,color"`
+	Count int           `bson:"-" jsonapi:"attr,count"`
+}
+
+func init() {
+	resourceTypes["tag"] = Tag{}
+	taskRelations["tags"] = loadTaskTags
+}
+
+// NewTag create a new tag.
+func NewTag(name string, color string) (*Tag, error) {
+	tag := &Tag{Name: name, Color: color}
+	if err := tag.Validate(); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// Validate checks attributes's integrity.
+func (t *Tag) Validate() error {
+	err := validator.New().Struct(t)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// ensureNameIndex create once by database the unique index of the tag names,
+// it guards the concurrent writes of a name.
+func ensureNameIndex(c *mgo.Collection) {
+	if _, ok := nameIndexes.Load(c.Database.Name); ok {
+		return
+	}
+	if err := c.EnsureIndex(mgo.Index{Key: []string{"name"}, Unique: true}); err != nil {
+		// The names are still counted before a write.
+		log.Printf("can't to index the tag names of %v (%v)", c.Database.Name, err)
+		return
+	}
+	nameIndexes.Store(c.Database.Name, true)
+}
+
+// Save persist the tag into the database.
+func (t *Tag) Save(tenant string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "tags")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Tag names are unique.
+	ensureNameIndex(c)
+	n, err := c.Find(bson.M{"name": t.Name}).Count()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrTagTaken
+	}
+
+	t.ID = bson.NewObjectId()
+	t.SID = t.ID.Hex()
+
+	if err := c.Insert(t); mgo.IsDup(err) {
+		return ErrTagTaken
+	} else if err != nil {
+		return fmt.Errorf("can't to persist the tag (%v)", err)
+	}
+	return nil
+}
+
+// Update rename or recolor an existing tag, tasks reference the tag by ID
+// so they see the change right away.
+func (t *Tag) Update(tenant string) error {
+	return t.UpdateAs(tenant, "")
+}
+
+// UpdateAs update a tag like Update, the count is the tasks visible to the
+// user.
+func (t *Tag) UpdateAs(tenant string, user string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "tags")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(t.SID) {
+		return mgo.ErrNotFound
+	}
+	t.ID = bson.ObjectIdHex(t.SID)
+
+	ensureNameIndex(c)
+	n, err := c.Find(bson.M{"name": t.Name, "_id": bson.M{"$ne": t.ID}}).Count()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrTagTaken
+	}
+
+	if err := c.UpdateId(t.ID, bson.M{"$set": bson.M{"name": t.Name, "color": t.Color}}); mgo.IsDup(err) {
+		return ErrTagTaken
+	} else if err == mgo.ErrNotFound {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("can't to persist the tag (%v)", err)
+	}
+	return countTags(tenant, user, []*Tag{t})
+}
+
+// SelectTag find a tag by ID.
+func SelectTag(tenant string, id string) (*Tag, error) {
+	return SelectTagAs(tenant, "", id)
+}
+
+// SelectTagAs find a tag by ID, the count is the tasks visible to the user.
+func SelectTagAs(tenant string, user string, id string) (*Tag, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "tags")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, mgo.ErrNotFound
+	}
+
+	t := &Tag{}
+	if err := c.FindId(bson.ObjectIdHex(id)).One(t); err != nil {
+		return nil, err
+	}
+	return t, countTags(tenant, user, []*Tag{t})
+}
+
+// SearchTags return all the tags sorted by name with their task count.
+func SearchTags(tenant string) ([]*Tag, error) {
+	return SearchTagsAs(tenant, "")
+}
+
+// SearchTagsAs return all the tags sorted by name, the count is the tasks
+// visible to the user.
+func SearchTagsAs(tenant string, user string) ([]*Tag, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "tags")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	tags := []*Tag{}
+	if err := c.Find(nil).Sort("name").All(&tags); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+	return tags, countTags(tenant, user, tags)
+}
+
+// DeleteTag remove a tag and take it off every task, the tasks changes are
+// recorded in the audit as done by the actor. Only the admins, who see every
+// task, remove the tags.
+func DeleteTag(tenant string, actor string, id string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "tags")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return mgo.ErrNotFound
+	}
+
+	// Untag the tasks first, a failure leaves the tag in place.
+	if err := auditedUpdateAll(c.Database.C("tasks"), actor, bson.M{"tags": id}, bson.M{"$pull": bson.M{"tags": id}}); err != nil {
+		return fmt.Errorf("can't to untag the tasks (%v)", err)
+	}
+	return c.RemoveId(bson.ObjectIdHex(id))
+}
+
+// countTags set the number of tasks visible to the user of each tag.
+func countTags(tenant string, user string, tags []*Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	// Get the database connection.
+	s, c, err := getDatabase(tenant)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var ids []string
+	for _, t := range tags {
+		ids = append(ids, t.SID)
+	}
+
+	var counts []struct {
+		ID    string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	match, err := visibleTo(c.Database, bson.M{"tags": bson.M{"$in": ids}}, user)
+	if err != nil {
+		return err
+	}
+	pipe := c.Pipe([]bson.M{
+		{"$match": match},
+		{"$unwind": "$tags"},
+		{"$match": bson.M{"tags": bson.M{"$in": ids}}},
+		{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+	})
+	if err := pipe.All(&counts); err != nil {
+		return fmt.Errorf("can't to count the tags (%v)", err)
+	}
+
+	for _, t := range tags {
+		t.Count = 0
+		for _, n := range counts {
+			if n.ID == t.SID {
+				t.Count = n.Count
+			}
+		}
+	}
+	return nil
+}
+
+// resolveTags checks the tags of the relationship exist and set the stored
+// tag IDs.
+func (t *Task) resolveTags(tenant string) error {
+	if t.Tags == nil {
+		return nil
+	}
+
+	ids := []string{}
+	for _, tag := range t.Tags {
+		if !bson.IsObjectIdHex(tag.SID) {
+			return fmt.Errorf("tag id value is not valid (%v)", tag.SID)
+		}
+		if !contains(ids, tag.SID) {
+			ids = append(ids, tag.SID)
+		}
+	}
+
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "tags")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	oids := make([]bson.ObjectId, len(ids))
+	for i, id := range ids {
+		oids[i] = bson.ObjectIdHex(id)
+	}
+	n, err := c.Find(bson.M{"_id": bson.M{"$in": oids}}).Count()
+	if err != nil {
+		return err
+	}
+	if n != len(ids) {
+		return fmt.Errorf("unknown tags %v", ids)
+	}
+
+	t.TagIDs = ids
+	return nil
+}
+
+// loadTaskTags fill the tags relationship of the tasks.
+func loadTaskTags(tenant string, user string, tasks []*Task) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "tags")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var oids []bson.ObjectId
+	for _, t := range tasks {
+		for _, id := range t.TagIDs {
+			oids = append(oids, bson.ObjectIdHex(id))
+		}
+	}
+
+	var tags []*Tag
+	if err := c.Find(bson.M{"_id": bson.M{"$in": oids}}).All(&tags); err != nil {
+		return err
+	}
+	byID := map[string]*Tag{}
+	for _, tag := range tags {
+		byID[tag.SID] = tag
+	}
+
+	for _, t := range tasks {
+		t.Tags = []*Tag{}
+		for _, id := range t.TagIDs {
+			if tag, ok := byID[id]; ok {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+	mgo "gopkg.in/mgo.v2"
+)
+
+// writeTagError write the error of a tag operation.
+func writeTagError(w http.ResponseWriter, title string, err error) {
+	switch err {
+	case mgo.ErrNotFound:
+		writeError(w, http.StatusNotFound, title, "tag not found")
+	case ErrTagTaken:
+		writeError(w, http.StatusConflict, title, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, title, err.Error())
+	}
+}
+
+// CreateTagAPI create a new tag with jsonapi params, the tags are shared by
+// the tenant so only the admins change them.
+func CreateTagAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	tag := new(Tag)
+	if err := populateModel(r.Body, w, tag); err != nil {
+		return
+	}
+
+	if err := validateModel(tag, w); err != nil {
+		return
+	}
+
+	// Save the tag.
+	if err := tag.Save(RequestTenant(r)); err != nil {
+		writeTagError(w, "Save Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, tag)
+}
+
+// UpdateTagAPI rename or recolor a tag.
+func UpdateTagAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	tag := new(Tag)
+	if err := populateModel(r.Body, w, tag); err != nil {
+		return
+	}
+
+	if err := validateModel(tag, w); err != nil {
+		return
+	}
+
+	// Update the tag.
+	if err := tag.UpdateAs(RequestTenant(r), accessUser(r)); err != nil {
+		writeTagError(w, "Update Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, tag)
+}
+
+// ReadTagAPI return a tag with its task count.
+func ReadTagAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	params, err := ParseParams(r, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	tag, err := SelectTagAs(RequestTenant(r), accessUser(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeTagError(w, "Read Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalOnePayload(w, tag)
+	})
+}
+
+// SearchTagAPI return all the tags with their task count.
+func SearchTagAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	params, err := ParseParams(r, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	tags, err := SearchTagsAs(RequestTenant(r), accessUser(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalManyPayload(w, tags, len(tags))
+	})
+}
+
+// DeleteTagAPI remove a tag from all the tasks and return a 204 (no-content)
+// response.
+func DeleteTagAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := DeleteTag(RequestTenant(r), RequestUser(r), mux.Vars(r)["sid"]); err != nil {
+		writeTagError(w, "Delete Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
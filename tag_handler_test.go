@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+)
+
+func TestCreateTagAPI(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/tags/", strings.NewReader("{\"data\": {\"type\": \"tag\", \"attributes\": {\"name\": \"create tag from jsonapi\",\"color\":\"#00ff00\"}}}"))
+	if err != nil {
+		t.Errorf("unexpected error (%v)", err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(CreateTagAPI).ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusCreated {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestReadTaskAPIWithTags(t *testing.T) {
+	tag := createTagOrFatal(t, "test tag included")
+	task := newTaskOrFatal(t, "test read task api with tags")
+	task.Tags = []*Tag{tag}
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	m := mux.NewRouter()
+	m.HandleFunc("/task/{query}", ReadTaskAPI)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/task/"+task.SID+"?include=tags", strings.NewReader(""))
+
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusOK {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+
+	find := new(Task)
+	if err := jsonapi.UnmarshalPayload(rr.Body, find); err != nil {
+		t.Errorf("unexpected error (%v)", err)
+	}
+	if len(find.Tags) != 1 || find.Tags[0].Name != tag.Name {
+		t.Errorf("expected the tag %v, got %v", tag.Name, find.Tags)
+	}
+}
+
+func TestCreateTagAPIDuplicateName(t *testing.T) {
+	createTagOrFatal(t, "test duplicate tag api")
+
+	req, _ := http.NewRequest(http.MethodPost, "/tags/", strings.NewReader(`{"data": {"type": "tag", "attributes": {"name": "test duplicate tag api"}}}`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(CreateTagAPI).ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestReadTagAPIUnknown(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/tags/{sid}", ReadTagAPI)
+
+	for _, id := range []string{"5a0c4b8e1d41c82f5c3b2a10", "invalid"} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/tags/"+id, strings.NewReader(""))
+		m.ServeHTTP(rr, req)
+
+		// Test status code.
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestUpdateTagAPIUnknown(t *testing.T) {
+	body := `{"data": {"type": "tag", "id": "5a0c4b8e1d41c82f5c3b2a10", "attributes": {"name": "test update unknown tag"}}}`
+	req, _ := http.NewRequest(http.MethodPatch, "/tags/", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(UpdateTagAPI).ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDeleteTagAPIUnknown(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/tags/{sid}", DeleteTagAPI)
+
+	for _, id := range []string{"5a0c4b8e1d41c82f5c3b2a10", "invalid"} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/tags/"+id, strings.NewReader(""))
+		m.ServeHTTP(rr, req)
+
+		// Test status code.
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != jsonapi.MediaType {
+			t.Errorf("expected the content type %v, got %v", jsonapi.MediaType, ct)
+		}
+	}
+}
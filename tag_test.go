@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+)
+
+func createTagOrFatal(t *testing.T, name string) *Tag {
+	tag, err := NewTag(name, "#ff0000")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := tag.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return tag
+}
+
+func TestNewTagWithInvalidColor(t *testing.T) {
+	if _, err := NewTag("test tag color", "red"); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestSaveExistingTag(t *testing.T) {
+	createTagOrFatal(t, "test existing tag")
+	tag, _ := NewTag("test existing tag", "")
+	if err := tag.Save(""); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestSearchTaskByTags(t *testing.T) {
+	home := createTagOrFatal(t, "test tag home")
+	work := createTagOrFatal(t, "test tag work")
+
+	task := newTaskOrFatal(t, "test task with tags")
+	task.Tags = []*Tag{home, work}
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	other := newTaskOrFatal(t, "test task with one tag")
+	other.Tags = []*Tag{home}
+	if err := other.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	s := &TaskSearch{All: true, Page: 1, Limit: 10, Tags: []string{home.SID, work.SID}}
+	if _, n, _ := s.Find(); n != 2 {
+		t.Errorf("expected 2 tasks with any tag, got %v", n)
+	}
+	s.AllTags = true
+	if _, n, _ := s.Find(); n != 1 {
+		t.Errorf("expected 1 task with all tags, got %v", n)
+	}
+
+	find, err := SelectTag("", home.SID)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if find.Count != 2 {
+		t.Errorf("expected tag count 2, got %v", find.Count)
+	}
+}
+
+func TestDeleteTagUntagTasks(t *testing.T) {
+	tag := createTagOrFatal(t, "test tag deleted")
+	task := newTaskOrFatal(t, "test task with deleted tag")
+	task.Tags = []*Tag{tag}
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if err := DeleteTag("", "", tag.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	find := selectTaskOrFatal(t, task.SID)
+	if len(find.TagIDs) != 0 {
+		t.Errorf("expected no tags, got %v", find.TagIDs)
+	}
+}
+
+func TestSaveTaskWithUnknownTag(t *testing.T) {
+	task := newTaskOrFatal(t, "test task with unknown tag")
+	task.Tags = []*Tag{{SID: "5a0c4b8e1d41c8a1b0f4a111"}}
+	if err := task.Save(""); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"log"
+	"reflect"
+	"sync"
 
 	"time"
 
+	"github.com/google/jsonapi"
 	"gopkg.in/go-playground/validator.v9"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -13,7 +17,24 @@ import (
 
 var validate *validator.Validate
 
-func getDatabase() (*mgo.Session, *mgo.Collection, error) {
+// ErrTitleTaken is returned when another task of the list have the title.
+var ErrTitleTaken = errors.New("task already exists in the list")
+
+// titleIndexes is the databases having the unique index of the titles.
+var titleIndexes sync.Map
+
+func getDatabase(tenant string) (*mgo.Session, *mgo.Collection, error) {
+	return getCollection(tenant, "tasks")
+}
+
+// getCollection connect to the database of the tenant and select a
+// collection.
+func getCollection(tenant string, name string) (*mgo.Session, *mgo.Collection, error) {
+	t, err := lookupTenant(tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Connection to mongodb server.
 	host := "localhost"
 	session, err := mgo.Dial(host)
@@ -25,19 +46,77 @@ func getDatabase() (*mgo.Session, *mgo.Collection, error) {
 	session.SetMode(mgo.Monotonic, true)
 
 	// Select the collection.
-	c := session.DB(os.Getenv("TASK_DB")).C("tasks")
+	c := session.DB(t.Database).C(name)
 
 	return session, c, nil
 }
 
 // Task is the type of a task.
 type Task struct {
-	ID        bson.ObjectId `bson:"_id,omitempty" `
-	SID       string        `bson:"sid,omitempty" jsonapi:"primary,task"`
-	Title     string        `bson:"title" validate:"required" jsonapi:"attr,title"`
-	Done      bool          `bson:"done" jsonapi:"attr,done"`
-	CreatedAt time.Time     `bson:"createdAt" jsonapi:"attr,created_at"`
-	UpdatedAt time.Time     `bson:"updatedAt" jsonsapi:"attr,updated_at"`
+	ID           bson.ObjectId `bson:"_id,omitempty" `
+	SID          string        `bson:"sid,omitempty" jsonapi:"primary,task"`
+	Title        string        `bson:"title" validate:"required" jsonapi:"attr,title"`
+	Done         bool          `bson:"done" jsonapi:"attr,done"`
+	CreatedAt    time.Time     `bson:"createdAt" jsonapi:"attr,created_at"`
+	UpdatedAt    time.Time     `bson:"updatedAt" jsonsapi:"attr,updated_at"`
+	DueAt        *time.Time    `bson:"dueAt,omitempty"`
+	DueDate      string        `bson:"-" validate:"omitempty,duedate" jsonapi:"attr,due_at" compute:"dueAt"`
+	Overdue      bool          `bson:"-" jsonapi:"attr,overdue" compute:"dueAt,done,status"`
+	Priority     string        `bson:"priority,omitempty" validate:"omitempty,oneof=none low medium high urgent" jsonapi:"attr,priority"`
+	PriorityRank int           `bson:"priorityRank"`
+	Position     float64       `bson:"position" jsonapi:"attr,position"`
+	// Description is stored as Markdown and rendered on request.
+	Description     string `bson:"description,omitempty" validate:"max=10000" jsonapi:"attr,description"`
+	DescriptionHTML string `bson:"-" jsonapi:"attr,description_html,omitempty" compute:"description"`
+	// Status follows the workflow, done is derived from it.
+	Status      string     `bson:"status" validate:"omitempty,status" jsonapi:"attr,status"`
+	CompletedAt *time.Time `bson:"completedAt,omitempty" jsonapi:"attr,completed_at,iso8601,omitempty"`
+	// Tags is only loaded when included, TagIDs is the stored relationship.
+	TagIDs []string `bson:"tags,omitempty"`
+	Tags   []*Tag   `bson:"-" jsonapi:"relation,tags" compute:"tags"`
+	// List is only loaded when included, ListID is the stored relationship.
+	ListID string `bson:"list,omitempty"`
+	List   *List  `bson:"-" jsonapi:"relation,list,omitempty" compute:"list"`
+	// Parent is only loaded when included, ParentID is the stored relationship.
+	ParentID string    `bson:"parent,omitempty"`
+	Parent   *Task     `bson:"-" jsonapi:"relation,parent,omitempty" compute:"parent"`
+	Subtasks *Progress `bson:"-"`
+	// BlockedBy is only loaded when included, BlockedByIDs is the stored
+	// relationship.
+	BlockedByIDs []string `bson:"blockedBy,omitempty"`
+	BlockedBy    []*Task  `bson:"-" jsonapi:"relation,blocked_by" compute:"blockedBy"`
+	Blocked      bool     `bson:"-" jsonapi:"attr,blocked" compute:"blockedBy"`
+	// Recurrence is an iCalendar RRULE, the series start at RecurrenceStart.
+	Recurrence      string     `bson:"recurrence,omitempty" validate:"omitempty,rrule" jsonapi:"attr,recurrence"`
+	RecurrenceStart *time.Time `bson:"recurrenceStart,omitempty"`
+	NextOccurrence  string     `bson:"nextOccurrence,omitempty"`
+	// PreviousOccurrence is the task the occurrence follows in its series,
+	// the occurrences keep the title of the series.
+	PreviousOccurrence string `bson:"previousOccurrence,omitempty"`
+	// NextPending is set while the next occurrence of the done task isn't
+	// created, the scheduler retries it.
+	NextPending bool `bson:"nextPending,omitempty"`
+	// LoggedMinutes is only changed by the time entries.
+	EstimateMinutes int `bson:"estimateMinutes,omitempty" validate:"min=0" jsonapi:"attr,estimate_minutes,omitempty"`
+	LoggedMinutes   int `bson:"loggedMinutes,omitempty" jsonapi:"attr,logged_minutes"`
+	// CreatedBy and Assignee are only loaded when included, CreatedByID and
+	// AssigneeID are the stored relationships.
+	CreatedByID string `bson:"createdBy,omitempty"`
+	CreatedBy   *User  `bson:"-" jsonapi:"relation,created_by,omitempty" compute:"createdBy"`
+	// UpdatedByID is the user of the last update.
+	UpdatedByID string `bson:"updatedBy,omitempty"`
+	AssigneeID  string `bson:"assignee,omitempty"`
+	Assignee    *User  `bson:"-" jsonapi:"relation,assignee,omitempty" compute:"assignee"`
+	// Shares is only changed by the share operations.
+	Shares []*Share `bson:"shares,omitempty"`
+	// Custom is the values of the custom fields by field name.
+	Custom map[string]interface{} `bson:"custom,omitempty" jsonapi:"attr,custom,omitempty"`
+	// Checklist is only changed by the checklist operations.
+	Checklist []*ChecklistItem `bson:"checklist,omitempty"`
+	Comments  []*Comment       `bson:"-" jsonapi:"relation,comments,omitempty" compute:"sid"`
+	// fields is the attributes and relationships of the update payload, nil
+	// when the task is updated as a whole.
+	fields map[string]bool
 }
 
 // NewTask create a new task.
@@ -53,6 +132,9 @@ func NewTask(title string) (*Task, error) {
 func (t *Task) Validate() error {
 	//errs := validator.Validate(t)
 	validate = validator.New()
+	validate.RegisterValidation("duedate", validateDueDate)
+	validate.RegisterValidation("status", validateStatus)
+	validate.RegisterValidation("rrule", validateRecurrence)
 	err := validate.Struct(t)
 	if err != nil {
 
@@ -64,11 +146,81 @@ func (t *Task) Validate() error {
 	return nil
 }
 
+// present checks the update payload have the attribute or relationship, a
+// task updated as a whole have every one.
+func (t *Task) present(name string) bool {
+	return t.fields == nil || t.fields[name]
+}
+
+// setComputed fill the attributes derived from the stored properties.
+func (t *Task) setComputed() {
+	t.DueDate = ""
+	t.Overdue = false
+	if t.DueAt != nil {
+		t.DueDate = t.DueAt.UTC().Format(time.RFC3339)
+		t.Overdue = !workflow.IsTerminal(t.currentStatus()) && t.DueAt.Before(time.Now())
+	}
+}
+
+// JSONAPIMeta return the meta of the task.
+func (t *Task) JSONAPIMeta() *jsonapi.Meta {
+	meta := jsonapi.Meta{}
+	if t.Subtasks != nil {
+		meta["subtasks"] = t.Subtasks
+	}
+	if p := checklistProgress(t.Checklist); p != nil {
+		meta["checklist"] = p
+	}
+	if len(meta) == 0 {
+		return nil
+	}
+	return &meta
+}
+
+// taskRelations load the related resources of tasks visible to the user by
+// relationship name.
+var taskRelations = map[string]func(tenant string, user string, tasks []*Task) error{}
+
+// TaskRelations return the name of the relationships a task can include.
+func TaskRelations() []string {
+	var names []string
+	for name := range taskRelations {
+		names = append(names, name)
+	}
+	return names
+}
+
+// LoadTaskRelations populate the requested relationships of the tasks, the
+// related tasks and lists the user can't view are left out.
+func LoadTaskRelations(tenant string, user string, tasks []*Task, names []string) error {
+	for _, name := range names {
+		load, ok := taskRelations[name]
+		if !ok {
+			return fmt.Errorf("unknown relationship %v", name)
+		}
+		if err := load(tenant, user, tasks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SelectTask find a task by ID or Title.
-func SelectTask(query string) (*Task, error) {
+func SelectTask(tenant string, query string) (*Task, error) {
+	return SelectTaskFields(tenant, query, nil)
+}
+
+// SelectTaskFields find a task by ID or Title and load only the given fields.
+func SelectTaskFields(tenant string, query string, fields []string) (*Task, error) {
+	return SelectTaskAs(tenant, "", query, fields)
+}
+
+// SelectTaskAs find a task visible to the user by ID or Title and load only
+// the given fields.
+func SelectTaskAs(tenant string, user string, query string, fields []string) (*Task, error) {
 
 	// Get the DB.
-	s, c, err := getDatabase()
+	s, c, err := getDatabase(tenant)
 	defer s.Close()
 	if err != nil {
 		return nil, err
@@ -81,12 +233,15 @@ func SelectTask(query string) (*Task, error) {
 
 	// Find the task.
 	t := &Task{}
-	var q *mgo.Query
+	bq := bson.M{"title": query}
 	if bson.IsObjectIdHex(query) {
-		q = c.Find(bson.M{"_id": bson.ObjectIdHex(query)})
-	} else {
-		q = c.Find(bson.M{"title": query})
+		bq = bson.M{"_id": bson.ObjectIdHex(query)}
 	}
+	if bq, err = visibleTo(c.Database, bq, user); err != nil {
+		return nil, err
+	}
+	q := c.Find(bq)
+	q = q.Select(Projection(Task{}, fields))
 
 	// Check the count and return an empty task.
 	if n, _ := q.Count(); n == 0 {
@@ -97,26 +252,116 @@ func SelectTask(query string) (*Task, error) {
 	if err = q.One(t); err != nil {
 		return nil, err
 	}
+	t.setComputed()
 
 	return t, nil
 }
 
+// TaskSearch holds the parameters of a tasks search.
+type TaskSearch struct {
+	// Tenant is the tenant of the tasks, the default tenant when empty.
+	Tenant string
+	Query  string
+	Done   bool
+	All    bool
+	Page   int
+	Limit  int
+	Fields []string
+	// Status filter the tasks by workflow status.
+	Status string
+	// ListID filter the tasks of a list.
+	ListID string
+	// ParentID filter the subtasks of a task.
+	ParentID string
+	// Actionable filter the tasks not done and not blocked.
+	Actionable bool
+	// Tags filter the tasks by tag ID, with any or all of them.
+	Tags    []string
+	AllTags bool
+	// Due filter the tasks by due date: overdue, today or week.
+	Due string
+	// Location is the time zone used to compute the days, UTC by default.
+	Location *time.Location
+	// User restrict the tasks to the ones visible to the user.
+	User string
+	// Assignee and CreatedBy filter the tasks by user ID.
+	Assignee  string
+	CreatedBy string
+	// Custom filter the tasks by custom field values.
+	Custom map[string]interface{}
+	// Sort is the list of JSON:API sort fields, prefixed by - for descending.
+	Sort []string
+}
+
 // SearchTask find all tasks with parameters.
-func SearchTask(query string, done bool, all bool, page int, limit int) ([]*Task, int, error) {
+func SearchTask(tenant string, query string, done bool, all bool, page int, limit int) ([]*Task, int, error) {
+	s := &TaskSearch{Tenant: tenant, Query: query, Done: done, All: all, Page: page, Limit: limit}
+	return s.Find()
+}
+
+// Find return a page of the matching tasks and the total count.
+func (ts *TaskSearch) Find() ([]*Task, int, error) {
 
 	// Get the DB.
-	s, c, err := getDatabase()
+	s, c, err := getDatabase(ts.Tenant)
 	defer s.Close()
 	if err != nil {
 		return nil, 0, err
 	}
-	reg := bson.RegEx{Pattern: query, Options: ""}
-	bq := bson.M{"title": reg}
+	reg := bson.RegEx{Pattern: ts.Query, Options: ""}
+	bq := bson.M{"$or": []bson.M{{"title": reg}, {"description": reg}}}
+
+	if !ts.All {
+		bq["done"] = ts.Done
+	}
+
+	if ts.Status != "" {
+		bq["status"] = ts.Status
+	}
+
+	if ts.ListID != "" {
+		bq["list"] = ts.ListID
+	}
+
+	if ts.ParentID != "" {
+		bq["parent"] = ts.ParentID
+	}
+
+	if ts.Actionable {
+		if err := actionableFilter(c, bq); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if len(ts.Tags) > 0 {
+		op := "$in"
+		if ts.AllTags {
+			op = "$all"
+		}
+		bq["tags"] = bson.M{op: ts.Tags}
+	}
+
+	if ts.Assignee != "" {
+		bq["assignee"] = ts.Assignee
+	}
+
+	if ts.CreatedBy != "" {
+		bq["createdBy"] = ts.CreatedBy
+	}
 
-	if !all {
-		bq["done"] = done
+	for name, v := range ts.Custom {
+		bq["custom."+name] = v
 	}
 
+	if ts.Due != "" {
+		if err := dueFilter(bq, ts.Due, ts.Location); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if bq, err = visibleTo(c.Database, bq, ts.User); err != nil {
+		return nil, 0, err
+	}
 	q := c.Find(bq)
 
 	n, err := q.Count()
@@ -124,35 +369,110 @@ func SearchTask(query string, done bool, all bool, page int, limit int) ([]*Task
 		return nil, 0, fmt.Errorf("unexpected error %v", err)
 	}
 
-	q.Sort("title", "_id").Limit(limit)
+	sort, err := sortFields(ts.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+	q.Select(Projection(Task{}, ts.Fields)).Sort(sort...).Limit(ts.Limit)
 
 	// To get the nth page:
-	q = q.Skip((page - 1) * limit)
+	q = q.Skip((ts.Page - 1) * ts.Limit)
 
 	var tasks []*Task
 	if err = q.All(&tasks); err != nil {
 		return nil, 0, fmt.Errorf("unexpected error %v", err)
 	}
+	for _, t := range tasks {
+		t.setComputed()
+	}
 
 	return tasks, n, nil
 }
 
+// checkNewTask checks the quota of the tenant and the title of a task before
+// inserting it, the titles are unique in a list except for the occurrences of
+// a series.
+func checkNewTask(tenant string, c *mgo.Collection, t *Task) error {
+	tn, err := lookupTenant(tenant)
+	if err != nil {
+		return err
+	}
+	if err := checkQuota(c, tn.MaxTasks); err != nil {
+		return err
+	}
+
+	if t.PreviousOccurrence != "" {
+		return nil
+	}
+	return checkTitle(c, t.Title, t.ListID, "")
+}
+
+// checkTitle return ErrTitleTaken when another task than id have the title in
+// the list, the occurrences of a series are left out. The unique index of the
+// titles by list guards the concurrent writes, each occurrence is unique by
+// the task it follows.
+func checkTitle(c *mgo.Collection, title string, list string, id bson.ObjectId) error {
+	if _, ok := titleIndexes.Load(c.Database.Name); !ok {
+		// The index of the older versions didn't leave out the occurrences.
+		if err := dropIndex(c, "list", "title"); err != nil {
+			log.Printf("can't to drop the older index of the task titles of %v (%v)", c.Database.Name, err)
+		}
+		// The duplicates of older versions prevent the index, the titles are
+		// still checked below.
+		if err := c.EnsureIndex(mgo.Index{Key: []string{"list", "title", "previousOccurrence"}, Unique: true}); err != nil {
+			log.Printf("can't to index the task titles of %v (%v)", c.Database.Name, err)
+		} else {
+			titleIndexes.Store(c.Database.Name, true)
+		}
+	}
+
+	q := bson.M{"title": title, "list": listKey(list), "previousOccurrence": nil}
+	if id.Valid() {
+		q["_id"] = bson.M{"$ne": id}
+	}
+	n, err := c.Find(q).Count()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrTitleTaken
+	}
+	return nil
+}
+
+// dropIndex remove the index of the keys when it exists.
+func dropIndex(c *mgo.Collection, key ...string) error {
+	indexes, err := c.Indexes()
+	if err != nil {
+		return err
+	}
+	for _, i := range indexes {
+		if reflect.DeepEqual(i.Key, key) {
+			return c.DropIndex(key...)
+		}
+	}
+	return nil
+}
+
 // Save persist the task into the database.
-func (t *Task) Save() error {
+func (t *Task) Save(tenant string) error {
 	// Get the database connection.
-	s, c, err := getDatabase()
+	s, c, err := getDatabase(tenant)
 	defer s.Close()
 	if err != nil {
 		return err
 	}
 
-	// Find an existing task with the same properties.
-	r, err := SelectTask(t.Title)
-	if err != nil {
+	if err := validateCustom(tenant, t.Custom); err != nil {
+		return err
+	}
+
+	if err := t.resolveList(tenant); err != nil {
 		return err
 	}
-	if (Task{}) != *r {
-		return fmt.Errorf("task already exists %v", r.SID)
+
+	if err := checkNewTask(tenant, c, t); err != nil {
+		return err
 	}
 
 	// Generete a mongoDB and Json ID.
@@ -160,20 +480,64 @@ func (t *Task) Save() error {
 	t.SID = t.ID.Hex()
 
 	t.CreatedAt = time.Now()
+	t.LoggedMinutes = 0
+	t.Shares = nil
+
+	if err := t.resolveParent(c); err != nil {
+		return err
+	}
+
+	if err := t.resolveBlockers(c); err != nil {
+		return err
+	}
+
+	if err := t.parseDueDate(); err != nil {
+		return err
+	}
+
+	if err := t.resolveTags(tenant); err != nil {
+		return err
+	}
+
+	if err := t.resolveAssignee(tenant); err != nil {
+		return err
+	}
+
+	t.startRecurrence()
+
+	// Start the task in the workflow.
+	if err := t.applyStatus(&Task{Status: workflow.Initial}); err != nil {
+		return err
+	}
+
+	// Put the task at the end.
+	t.PriorityRank = priorityRank(t.Priority)
+	if t.Position, err = nextPosition(c, t.ListID); err != nil {
+		return err
+	}
 
 	// Persist the task.
 	err = c.Insert(&t)
-	if err != nil {
+	if mgo.IsDup(err) {
+		return ErrTitleTaken
+	} else if err != nil {
 		return fmt.Errorf("can't to persist the task (%v)", err)
 	}
+	t.setComputed()
 
-	return nil
+	return auditTask(c, AuditCreate, t.CreatedByID, nil, t.ID)
 }
 
 // Update persist an existing task with new properties
-func (t *Task) Update() error {
+func (t *Task) Update(tenant string) error {
+	return t.UpdateAs(tenant, "")
+}
+
+// UpdateAs persist the changes of a task visible to the user. The creator of
+// a task can't change.
+func (t *Task) UpdateAs(tenant string, user string) error {
 	// Get the database connection.
-	s, c, err := getDatabase()
+	s, c, err := getDatabase(tenant)
 	defer s.Close()
 	if err != nil {
 		return err
@@ -188,50 +552,266 @@ func (t *Task) Update() error {
 		}
 	}
 
-	// Persist the task.
-	if err := c.UpdateId(t.ID, bson.M{"$set": bson.M{"title": t.Title, "done": t.Done, "updatedAt": time.Now()}}); err != nil {
-		return fmt.Errorf("can't to persist the task (%v)", err)
+	// Check the role of the user, the editors can change the task.
+	old := &Task{}
+	fields := bson.M{"status": 1, "done": 1, "completedAt": 1, "recurrence": 1, "recurrenceStart": 1, "previousOccurrence": 1, "parent": 1, "blockedBy": 1, "dueAt": 1, "priority": 1, "description": 1, "estimateMinutes": 1, "custom": 1}
+	for k := range accessFields {
+		fields[k] = 1
+	}
+	if err := c.FindId(t.ID).Select(fields).One(old); err == mgo.ErrNotFound {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("can't find the task %v (%v)", t.SID, err)
+	}
+	role, err := taskRole(c.Database, old, user)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return mgo.ErrNotFound
 	}
+	if !grants(role, RoleEditor) {
+		return ErrForbidden
+	}
+	t.CreatedByID = old.CreatedByID
 
-	return nil
-}
+	// Moving the task to another list needs to edit the list.
+	if t.List != nil && t.List.SID != old.ListID && t.List.SID != "" {
+		role, err := ListRole(tenant, user, t.List.SID)
+		if err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+		if err == nil && !grants(role, RoleEditor) {
+			return ErrForbidden
+		}
+	}
 
-// Delete remove an existing task.
-func (t *Task) Delete() error {
-	// Get the database connection.
-	s, c, err := getDatabase()
-	defer s.Close()
-	if err != nil {
+	// Moving the task under another task needs to edit it, and being blocked
+	// by a task needs to view it.
+	if t.Parent != nil && t.Parent.SID != "" && t.Parent.SID != old.ParentID {
+		if err := requireTaskRoles(c, user, []string{t.Parent.SID}, RoleEditor); err != nil {
+			return err
+		}
+	}
+	var blockers []string
+	for _, b := range t.BlockedBy {
+		if !contains(old.BlockedByIDs, b.SID) {
+			blockers = append(blockers, b.SID)
+		}
+	}
+	if err := requireTaskRoles(c, user, blockers, RoleViewer); err != nil {
 		return err
 	}
 
-	// Remove the task
-	err = c.Remove(bson.M{"_id": t.ID})
-	if err != nil {
+	if err := validateCustom(tenant, t.Custom); err != nil {
 		return err
 	}
 
-	return nil
-}
+	if err := t.parseDueDate(); err != nil {
+		return err
+	}
+	if !t.present("due_at") {
+		t.DueAt = old.DueAt
+	}
+	if !t.present("priority") {
+		t.Priority = old.Priority
+	}
+	if !t.present("description") {
+		t.Description = old.Description
+	}
+	if !t.present("recurrence") {
+		t.Recurrence = old.Recurrence
+	}
+	if !t.present("estimate_minutes") {
+		t.EstimateMinutes = old.EstimateMinutes
+	}
+	if !t.present("custom") {
+		t.Custom = old.Custom
+	}
 
-// DeleteTask remove a task.
-func DeleteTask(id string) error {
-	// Get the database connection.
-	s, c, err := getDatabase()
-	defer s.Close()
-	if err != nil {
+	// The unmarshal skips the null and empty relationships of the payload,
+	// they clear the relationship.
+	if t.Tags == nil && t.fields["tags"] {
+		t.Tags = []*Tag{}
+	}
+	if t.List == nil && t.fields["list"] {
+		t.List = &List{}
+	}
+	if t.Parent == nil && t.fields["parent"] {
+		t.Parent = &Task{}
+	}
+	if t.BlockedBy == nil && t.fields["blocked_by"] {
+		t.BlockedBy = []*Task{}
+	}
+
+	if err := t.resolveTags(tenant); err != nil {
+		return err
+	}
+
+	if err := t.resolveList(tenant); err != nil {
 		return err
 	}
 
-	// Remove the task
-	if !bson.IsObjectIdHex(id) {
-		return fmt.Errorf("id value is not valid (%v)", id)
+	// The titles are unique in the list of the task, except for the
+	// occurrences of a series.
+	list := old.ListID
+	if t.List != nil {
+		list = t.ListID
 	}
-	oid := bson.ObjectIdHex(id)
-	err = c.Remove(bson.M{"_id": oid})
+	if old.PreviousOccurrence == "" {
+		if err := checkTitle(c, t.Title, list, t.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := t.resolveAssignee(tenant); err != nil {
+		return err
+	}
+
+	if err := t.resolveParent(c); err != nil {
+		return err
+	}
+
+	if err := t.resolveBlockers(c); err != nil {
+		return err
+	}
+
+	// Keep the status when the update omits it, the done attribute only
+	// changes the status when it is sent.
+	if !t.present("status") && !t.present("done") {
+		t.Status = old.currentStatus()
+	}
+
+	// Check the status transition.
+	if err := t.applyStatus(old); err != nil {
+		return err
+	}
+	// Record the changes, by the calling user when known.
+	actor := t.UpdatedByID
+	if actor == "" {
+		actor = user
+	}
+
+	completed := t.Done && old.currentStatus() != workflow.Done
+	if completed {
+		if err := t.completeSubtasks(c, user, actor); err != nil {
+			return err
+		}
+	}
+
+	if t.RecurrenceStart == nil {
+		t.RecurrenceStart = old.RecurrenceStart
+	}
+	t.startRecurrence()
+
+	// Persist the task.
+	t.UpdatedAt = time.Now()
+	t.PriorityRank = priorityRank(t.Priority)
+	set := bson.M{"title": t.Title, "done": t.Done, "status": t.Status, "updatedAt": t.UpdatedAt}
+	unset := bson.M{}
+	if t.present("estimate_minutes") {
+		set["estimateMinutes"] = t.EstimateMinutes
+	}
+	if t.present("description") {
+		set["description"] = t.Description
+	}
+	if t.present("priority") {
+		set["priority"] = t.Priority
+		set["priorityRank"] = t.PriorityRank
+	}
+	if len(t.Custom) > 0 {
+		set["custom"] = t.Custom
+	} else if t.present("custom") {
+		unset["custom"] = ""
+	}
+	if t.Assignee != nil && t.AssigneeID != "" {
+		set["assignee"] = t.AssigneeID
+	} else if t.Assignee != nil {
+		unset["assignee"] = ""
+	}
+	if t.Tags != nil && len(t.TagIDs) > 0 {
+		set["tags"] = t.TagIDs
+	} else if t.Tags != nil {
+		unset["tags"] = ""
+	}
+	if t.List != nil && t.ListID != "" {
+		set["list"] = t.ListID
+	} else if t.List != nil {
+		unset["list"] = ""
+	}
+	if t.Parent != nil {
+		set["parent"] = t.ParentID
+	}
+	if t.BlockedBy != nil {
+		set["blockedBy"] = t.BlockedByIDs
+	}
+	if t.DueAt != nil {
+		set["dueAt"] = t.DueAt
+	} else if t.present("due_at") {
+		unset["dueAt"] = ""
+	}
+	if t.CompletedAt != nil {
+		set["completedAt"] = t.CompletedAt
+	} else {
+		unset["completedAt"] = ""
+	}
+	if t.Recurrence != "" {
+		set["recurrence"] = t.Recurrence
+		set["recurrenceStart"] = t.RecurrenceStart
+	} else if t.present("recurrence") {
+		unset["recurrence"] = ""
+		unset["recurrenceStart"] = ""
+	}
+	if t.UpdatedByID != "" {
+		set["updatedBy"] = t.UpdatedByID
+	} else {
+		unset["updatedBy"] = ""
+	}
+	// The next occurrence stays pending until it is created.
+	if completed && t.Recurrence != "" {
+		set["nextPending"] = true
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	before, err := taskDocument(c, t.ID)
 	if err != nil {
 		return err
 	}
+	if err := c.UpdateId(t.ID, update); mgo.IsDup(err) {
+		return ErrTitleTaken
+	} else if err != nil {
+		return fmt.Errorf("can't to persist the task (%v)", err)
+	}
+	t.setComputed()
+
+	if err := auditTask(c, AuditUpdate, actor, before, t.ID); err != nil {
+		return err
+	}
+
+	if err := rescheduleReminders(c.Database, t.SID, t.DueAt); err != nil {
+		return err
+	}
+
+	// A done recurring task brings its next occurrence, the task stays done
+	// when it fails and the scheduler retries it.
+	if completed {
+		if err := scheduleNext(tenant, c, actor, t.ID); err != nil {
+			log.Printf("can't to schedule the next occurrence of %v, retried later (%v)", t.SID, err)
+		}
+	}
 
 	return nil
 }
+
+// Delete remove an existing task like DeleteTask, the removal is recorded in
+// the audit as done by the actor.
+func (t *Task) Delete(tenant string, actor string) error {
+	return DeleteTaskTreeAs(tenant, "", actor, t.ID.Hex(), false)
+}
+
+// DeleteTask remove a task, its subtasks are moved under its parent.
+func DeleteTask(tenant string, id string) error {
+	return DeleteTaskTree(tenant, id, false)
+}
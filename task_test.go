@@ -18,14 +18,14 @@ func createTaskOrFatal(t *testing.T, title string) *Task {
 	if err != nil {
 		t.Fatalf("unexpected error : %v", err)
 	}
-	if err := task.Save(); err != nil {
+	if err := task.Save(""); err != nil {
 		t.Fatalf("unexpected error : %v", err)
 	}
 	return task
 }
 
 func selectTaskOrFatal(t *testing.T, title string) *Task {
-	task, err := SelectTask(title)
+	task, err := SelectTask("", title)
 	if err != nil {
 		t.Fatalf("unexpected error (%v)", err)
 	}
@@ -49,7 +49,7 @@ func TestNewTaskWithEmptyTitle(t *testing.T) {
 
 func TestSaveTask(t *testing.T) {
 	task := newTaskOrFatal(t, "testing task")
-	err := task.Save()
+	err := task.Save("")
 	if err != nil {
 		t.Errorf("unexpected error : %v", err)
 	}
@@ -57,7 +57,7 @@ func TestSaveTask(t *testing.T) {
 
 func TestFindTaskByTitle(t *testing.T) {
 	task := createTaskOrFatal(t, "test select task by title")
-	find, err := SelectTask(task.Title)
+	find, err := SelectTask("", task.Title)
 	if err != nil {
 		t.Errorf("unexpected error (%v)", err)
 	}
@@ -68,7 +68,7 @@ func TestFindTaskByTitle(t *testing.T) {
 
 func TestSelectTaskByID(t *testing.T) {
 	task := createTaskOrFatal(t, "test select task by id")
-	find, err := SelectTask(task.SID)
+	find, err := SelectTask("", task.SID)
 	if err != nil {
 		t.Errorf("unexpected error (%v)", err)
 	}
@@ -80,7 +80,7 @@ func TestSelectTaskByID(t *testing.T) {
 func TestSearchTask(t *testing.T) {
 
 	// Check search by title.
-	tasks, n, err := SearchTask("search", false, true, 1, 10)
+	tasks, n, err := SearchTask("", "search", false, true, 1, 10)
 	if err != nil {
 		t.Errorf("unexpected error (%v)", err)
 	}
@@ -95,25 +95,25 @@ func TestSearchTask(t *testing.T) {
 	}
 
 	// Check the pagination.
-	tasks2, n, err := SearchTask("search", false, true, 2, 10)
+	tasks2, n, err := SearchTask("", "search", false, true, 2, 10)
 	if reflect.DeepEqual(tasks, tasks2) {
 		t.Errorf("page 1 is not different to page 2")
 	}
 
 	// Check the done task.
-	tasks, n, err = SearchTask("search", true, false, 2, 10)
+	tasks, n, err = SearchTask("", "search", true, false, 2, 10)
 	if n != 50 {
 		t.Errorf("expected 50 done task, got %v", n)
 	}
 
 	// Check the not done task.
-	tasks, n, err = SearchTask("search", false, false, 2, 10)
+	tasks, n, err = SearchTask("", "search", false, false, 2, 10)
 	if n != 50 {
 		t.Errorf("expected 50 not done task, got %v", n)
 	}
 
 	// Test empty query
-	tasks, n, err = SearchTask("", false, false, 2, 10)
+	tasks, n, err = SearchTask("", "", false, false, 2, 10)
 	if n == 0 {
 		t.Errorf("expected more than 0, got %v", n)
 	}
@@ -121,7 +121,7 @@ func TestSearchTask(t *testing.T) {
 
 func TestSaveNewExistingTask(t *testing.T) {
 	task := newTaskOrFatal(t, "testing task")
-	err := task.Save()
+	err := task.Save("")
 	if err == nil {
 		t.Errorf("expected error (%v)", err)
 	}
@@ -134,7 +134,7 @@ func TestUpdateTask(t *testing.T) {
 	// Update the title.
 	title := "test task with an updated title"
 	task.Title = title
-	err := task.Update()
+	err := task.Update("")
 	if err != nil {
 		t.Errorf("unexpected error (%v)", err)
 	}
@@ -148,7 +148,7 @@ func TestUpdateTask(t *testing.T) {
 
 func TestUpdateNewTask(t *testing.T) {
 	task := newTaskOrFatal(t, "testing task")
-	err := task.Update()
+	err := task.Update("")
 	if err == nil {
 		t.Errorf("expected an error, got %v", err)
 	}
@@ -158,19 +158,19 @@ func TestDeleteTask(t *testing.T) {
 	title := "test delete task"
 	task := createTaskOrFatal(t, title)
 
-	if err := DeleteTask(task.SID); err != nil {
+	if err := DeleteTask("", task.SID); err != nil {
 		t.Errorf("unexpected error (%v)", err)
 	}
 
 	dt := selectTaskOrFatal(t, title)
-	if (Task{}) != *dt {
+	if dt.ID.Valid() {
 		t.Errorf("expected an empty task, got %v", dt)
 	}
 }
 
 func TestDeleteNewTask(t *testing.T) {
 	task := newTaskOrFatal(t, "test delete new task")
-	if err := DeleteTask(task.SID); err == nil {
+	if err := DeleteTask("", task.SID); err == nil {
 		t.Errorf("expected an error, got %v", err)
 	}
 }
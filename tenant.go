@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrUnknownTenant is returned for a tenant out of the configuration.
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// ErrQuotaExceeded is returned when a tenant reached one of its quotas.
+var ErrQuotaExceeded = errors.New("tenant quota exceeded")
+
+// ErrWrongTenant is returned when the credentials of a tenant are used on the
+// host of another tenant.
+var ErrWrongTenant = errors.New("the credentials are not valid for this tenant")
+
+// tenantName is the format of the tenant names, names are used in the blob
+// keys.
+var tenantName = regexp.MustCompile("^[a-z0-9-]{1,40}$")
+
+// Tenant is an isolated workspace, its data is kept in its own database. The
+// default tenant has no name and use the TASK_DB database.
+type Tenant struct {
+	Name     string `json:"name"`
+	Database string `json:"database"`
+	// Hosts is the host names of the requests resolved to the tenant.
+	Hosts []string `json:"hosts"`
+	// MaxTasks, MaxUsers and MaxStorage (attachments bytes) are the quotas
+	// of the tenant, 0 is unlimited.
+	MaxTasks   int   `json:"maxTasks"`
+	MaxUsers   int   `json:"maxUsers"`
+	MaxStorage int64 `json:"maxStorage"`
+}
+
+// tenants is the configured tenants by name.
+var tenants = map[string]*Tenant{}
+
+// LoadTenants read the tenants from a JSON file holding a list of tenants.
+func LoadTenants(path string) (map[string]*Tenant, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the tenants %v (%v)", path, err)
+	}
+
+	var list []*Tenant
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("can't decode the tenants %v (%v)", path, err)
+	}
+
+	byName := map[string]*Tenant{}
+	hosts := map[string]string{}
+	for _, t := range list {
+		if !tenantName.MatchString(t.Name) {
+			return nil, fmt.Errorf("tenant name %q is not valid", t.Name)
+		}
+		if _, ok := byName[t.Name]; ok {
+			return nil, fmt.Errorf("tenant %v is declared twice", t.Name)
+		}
+		if t.Database == "" {
+			return nil, fmt.Errorf("tenant %v have no database", t.Name)
+		}
+		for _, h := range t.Hosts {
+			h = strings.ToLower(h)
+			if other, ok := hosts[h]; ok {
+				return nil, fmt.Errorf("host %v is used by the tenants %v and %v", h, other, t.Name)
+			}
+			hosts[h] = t.Name
+		}
+		byName[t.Name] = t
+	}
+	return byName, nil
+}
+
+// lookupTenant return a tenant by name, the empty name is the default tenant.
+func lookupTenant(name string) (*Tenant, error) {
+	if name == "" {
+		return &Tenant{Database: os.Getenv("TASK_DB")}, nil
+	}
+	t, ok := tenants[name]
+	if !ok {
+		return nil, ErrUnknownTenant
+	}
+	return t, nil
+}
+
+// TenantNames return the name of every tenant, the default tenant included.
+func TenantNames() []string {
+	names := []string{""}
+	for name := range tenants {
+		names = append(names, name)
+	}
+	return names
+}
+
+// hostTenant return the tenant of a host, the default tenant when no tenant
+// declares the host.
+func hostTenant(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, t := range tenants {
+		if contains(t.Hosts, host) {
+			return t.Name
+		}
+	}
+	return ""
+}
+
+// resolveTenant return the tenant of a request: the tenant of the credentials,
+// which must match the tenant of the host. The bootstrap admin key isn't bound
+// to a tenant and use the X-Tenant header or the tenant of the host.
+func resolveTenant(r *http.Request, id *Identity) (string, error) {
+	host := hostTenant(r.Host)
+	if id.UserID == "" && id.Can(ScopeAdmin) {
+		if name := r.Header.Get("X-Tenant"); name != "" {
+			_, err := lookupTenant(name)
+			return name, err
+		}
+		return host, nil
+	}
+	if host != "" && host != id.Tenant {
+		return "", ErrWrongTenant
+	}
+	if _, err := lookupTenant(id.Tenant); err != nil {
+		return "", err
+	}
+	return id.Tenant, nil
+}
+
+// blobTenantKey return the blob key of a content in a tenant, the blob stores
+// are shared by the tenants.
+func blobTenantKey(tenant string, hash string) string {
+	if tenant == "" {
+		return hash
+	}
+	return tenant + "_" + hash
+}
+
+// checkQuota return ErrQuotaExceeded when the collection already have max
+// documents, a max of 0 is unlimited.
+func checkQuota(c *mgo.Collection, max int) error {
+	if max == 0 {
+		return nil
+	}
+	n, err := c.Count()
+	if err != nil {
+		return err
+	}
+	if n >= max {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// checkStorageQuota return ErrQuotaExceeded when adding size bytes of
+// attachments exceeds the storage of the tenant.
+func checkStorageQuota(tenant string, c *mgo.Collection, size int64) error {
+	t, err := lookupTenant(tenant)
+	if err != nil || t.MaxStorage == 0 {
+		return err
+	}
+
+	var used []struct {
+		Size int64 `bson:"size"`
+	}
+	pipe := c.Pipe([]bson.M{{"$group": bson.M{"_id": nil, "size": bson.M{"$sum": "$size"}}}})
+	if err := pipe.All(&used); err != nil {
+		return fmt.Errorf("can't to compute the storage (%v)", err)
+	}
+	if len(used) > 0 {
+		size += used[0].Size
+	}
+	if size > t.MaxStorage {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// tenantMatch return the value matching the documents of a tenant in a
+// query, the documents of the default tenant have no tenant field.
+func tenantMatch(tenant string) interface{} {
+	if tenant == "" {
+		return nil
+	}
+	return tenant
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+)
+
+// withTenants replace the configured tenants until the returned function is
+// called.
+func withTenants(ts ...*Tenant) func() {
+	old := tenants
+	tenants = map[string]*Tenant{}
+	for _, t := range ts {
+		tenants[t.Name] = t
+	}
+	return func() { tenants = old }
+}
+
+func TestLoadTenants(t *testing.T) {
+	f, err := ioutil.TempFile("", "tenants")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	for content, valid := range map[string]bool{
+		`[{"name": "acme", "database": "acme", "hosts": ["acme.example.com"], "maxTasks": 10}]`: true,
+		`[{"name": "Acme Inc", "database": "acme"}]`:                                            false,
+		`[{"name": "acme"}]`: false,
+		`[{"name": "acme", "database": "acme", "hosts": ["a.example.com"]}, {"name": "beta", "database": "beta", "hosts": ["A.example.com"]}]`: false,
+	} {
+		if err := ioutil.WriteFile(f.Name(), []byte(content), 0600); err != nil {
+			t.Fatalf("unexpected error : %v", err)
+		}
+		ts, err := LoadTenants(f.Name())
+		if valid && (err != nil || ts["acme"].MaxTasks != 10) {
+			t.Errorf("expected %v to load, got %v", content, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("expected an error for %v", content)
+		}
+	}
+}
+
+func TestResolveTenant(t *testing.T) {
+	defer withTenants(&Tenant{Name: "acme", Database: "acme", Hosts: []string{"acme.example.com"}})()
+
+	admin := &Identity{Scopes: []string{ScopeAdmin}}
+	user := &Identity{UserID: "alice", Tenant: "acme", Scopes: []string{ScopeTasksWrite}}
+
+	for _, tc := range []struct {
+		id       *Identity
+		host     string
+		header   string
+		expected string
+		err      bool
+	}{
+		{user, "acme.example.com:8000", "", "acme", false},
+		{user, "api.example.com", "", "acme", false},
+		{&Identity{UserID: "bob"}, "acme.example.com", "", "", true},
+		{&Identity{UserID: "bob", Tenant: "removed"}, "api.example.com", "", "", true},
+		{admin, "ACME.example.com", "", "acme", false},
+		{admin, "api.example.com", "acme", "acme", false},
+		{admin, "api.example.com", "unknown", "", true},
+	} {
+		r, _ := http.NewRequest(http.MethodGet, "/task/", nil)
+		r.Host = tc.host
+		if tc.header != "" {
+			r.Header.Set("X-Tenant", tc.header)
+		}
+		tenant, err := resolveTenant(r, tc.id)
+		if (err != nil) != tc.err || (err == nil && tenant != tc.expected) {
+			t.Errorf("%v on %v : expected %q (error %v), got %q (%v)", tc.id.UserID, tc.host, tc.expected, tc.err, tenant, err)
+		}
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	defer withTenants(&Tenant{Name: "acme", Database: os.Getenv("TASK_DB") + "-acme", MaxTasks: 1})()
+
+	s, c, err := getDatabase("acme")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer s.Close()
+	c.RemoveAll(nil)
+
+	task := newTaskOrFatal(t, "test tenant isolation")
+	if err := task.Save("acme"); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if r, _ := SelectTask("", task.SID); r.ID.Valid() {
+		t.Errorf("expected the task of acme to be hidden to the default tenant")
+	}
+	if r, _ := SelectTask("acme", task.SID); !r.ID.Valid() {
+		t.Errorf("expected the task to be found in acme")
+	}
+
+	other := newTaskOrFatal(t, "test tenant quota")
+	if err := other.Save("acme"); err != ErrQuotaExceeded {
+		t.Errorf("expected error %v, got %v", ErrQuotaExceeded, err)
+	}
+	if _, err := lookupTenant("unknown"); err != ErrUnknownTenant {
+		t.Errorf("expected error %v, got %v", ErrUnknownTenant, err)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrTimerRunning is returned when a user start a second timer on a task.
+var ErrTimerRunning = errors.New("a timer is already running on the task")
+
+// ErrNoTimer is returned when a user stop a timer not started.
+var ErrNoTimer = errors.New("no timer is running on the task")
+
+// ErrNoMinutes is returned when a manual time entry doesn't log any time.
+var ErrNoMinutes = errors.New("minutes must be a positive number")
+
+// runningIndexes is the databases having the unique index of the running
+// timers.
+var runningIndexes sync.Map
+
+// Time report groupings.
+const (
+	ReportByTask = "task"
+	ReportByTag  = "tag"
+	ReportByList = "list"
+)
+
+// TimeEntry is some time a user worked on a task, a running timer has no end.
+type TimeEntry struct {
+	ID        bson.ObjectId `bson:"_id,omitempty"`
+	SID       string        `bson:"sid,omitempty" jsonapi:"primary,time-entry"`
+	TaskID    string        `bson:"task" jsonapi:"attr,task_id"`
+	User      string        `bson:"user" jsonapi:"attr,user"`
+	StartedAt time.Time     `bson:"startedAt" jsonapi:"attr,started_at,iso8601"`
+	EndedAt   *time.Time    `bson:"endedAt,omitempty" jsonapi:"attr,ended_at,iso8601,omitempty"`
+	Minutes   int           `bson:"minutes" validate:"min=0,max=100000" jsonapi:"attr,minutes"`
+	Note      string        `bson:"note,omitempty" validate:"max=1000" jsonapi:"attr,note,omitempty"`
+	// Running is set until the timer is stopped, a user have a single
+	// running timer by task.
+	Running bool `bson:"running,omitempty"`
+}
+
+// TimeReportRow is the time logged on a task, a tag or a list.
+type TimeReportRow struct {
+	Key     string `jsonapi:"primary,time-report"`
+	Name    string `jsonapi:"attr,name"`
+	Minutes int    `jsonapi:"attr,minutes"`
+	Entries int    `jsonapi:"attr,entries"`
+}
+
+func init() {
+	resourceTypes["time-entry"] = TimeEntry{}
+}
+
+// Validate checks attributes's integrity.
+func (e *TimeEntry) Validate() error {
+	err := validator.New().Struct(e)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// checkTask checks the task of the entry exists.
+func (e *TimeEntry) checkTask(tenant string) error {
+	task, err := SelectTaskFields(tenant, e.TaskID, []string{})
+	if err != nil {
+		return err
+	}
+	if !task.ID.Valid() {
+		return mgo.ErrNotFound
+	}
+	return nil
+}
+
+// logTime add the minutes to the time logged on the task by the user.
+func logTime(db *mgo.Database, user string, taskID string, minutes int) error {
+	if minutes == 0 {
+		return nil
+	}
+	return auditedUpdate(db.C("tasks"), user, bson.M{"sid": taskID}, bson.M{"$inc": bson.M{"loggedMinutes": minutes}})
+}
+
+// ensureRunningIndex create once by database the unique index of the running
+// timers of a user by task. mgo.Index have no partial filter, the index is
+// created by command.
+func ensureRunningIndex(c *mgo.Collection) {
+	if _, ok := runningIndexes.Load(c.Database.Name); ok {
+		return
+	}
+	index := bson.M{
+		"key":                     bson.D{{Name: "task", Value: 1}, {Name: "user", Value: 1}},
+		"name":                    "task_1_user_1_running",
+		"unique":                  true,
+		"partialFilterExpression": bson.M{"running": true},
+	}
+	cmd := bson.D{{Name: "createIndexes", Value: c.Name}, {Name: "indexes", Value: []bson.M{index}}}
+	if err := c.Database.Run(cmd, nil); err != nil {
+		// The running timers are still counted before a start.
+		log.Printf("can't to index the running timers of %v (%v)", c.Database.Name, err)
+		return
+	}
+	runningIndexes.Store(c.Database.Name, true)
+}
+
+// StartTimer start a timer of the user on the task.
+func StartTimer(tenant string, taskID string, user string) (*TimeEntry, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "timeEntries")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	e := &TimeEntry{TaskID: taskID, User: user, StartedAt: time.Now().UTC(), Running: true}
+	if err := e.checkTask(tenant); err != nil {
+		return nil, err
+	}
+
+	ensureRunningIndex(c)
+
+	n, err := c.Find(bson.M{"task": taskID, "user": user, "endedAt": bson.M{"$exists": false}}).Count()
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, ErrTimerRunning
+	}
+
+	e.ID = bson.NewObjectId()
+	e.SID = e.ID.Hex()
+	if err := c.Insert(e); mgo.IsDup(err) {
+		return nil, ErrTimerRunning
+	} else if err != nil {
+		return nil, fmt.Errorf("can't to persist the time entry (%v)", err)
+	}
+	return e, nil
+}
+
+// StopTimer stop the running timer of the user on the task and log the time.
+func StopTimer(tenant string, taskID string, user string) (*TimeEntry, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "timeEntries")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	running := bson.M{"task": taskID, "user": user, "endedAt": bson.M{"$exists": false}}
+	e := &TimeEntry{}
+	if err := c.Find(running).One(e); err == mgo.ErrNotFound {
+		return nil, ErrNoTimer
+	} else if err != nil {
+		return nil, err
+	}
+
+	end := time.Now().UTC()
+	e.EndedAt = &end
+	e.Minutes = int(end.Sub(e.StartedAt).Round(time.Minute) / time.Minute)
+	e.Running = false
+
+	// Only the first stop of concurrent requests log the time.
+	running["_id"] = e.ID
+	if err := c.Update(running, bson.M{"$set": bson.M{"endedAt": e.EndedAt, "minutes": e.Minutes}, "$unset": bson.M{"running": ""}}); err == mgo.ErrNotFound {
+		return nil, ErrNoTimer
+	} else if err != nil {
+		return nil, fmt.Errorf("can't to stop the timer (%v)", err)
+	}
+
+	if err := logTime(c.Database, user, taskID, e.Minutes); err != nil {
+		return nil, fmt.Errorf("can't to log the time (%v)", err)
+	}
+	return e, nil
+}
+
+// Save persist a manual time entry and log the time on the task. The entry
+// ends now when no start is given.
+func (e *TimeEntry) Save(tenant string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "timeEntries")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if e.Minutes < 1 {
+		return ErrNoMinutes
+	}
+	if err := e.checkTask(tenant); err != nil {
+		return err
+	}
+
+	if e.StartedAt.IsZero() {
+		e.StartedAt = time.Now().Add(-time.Duration(e.Minutes) * time.Minute)
+	}
+	e.StartedAt = e.StartedAt.UTC()
+	end := e.StartedAt.Add(time.Duration(e.Minutes) * time.Minute)
+	e.EndedAt = &end
+
+	e.ID = bson.NewObjectId()
+	e.SID = e.ID.Hex()
+	if err := c.Insert(e); err != nil {
+		return fmt.Errorf("can't to persist the time entry (%v)", err)
+	}
+
+	if err := logTime(c.Database, e.User, e.TaskID, e.Minutes); err != nil {
+		return fmt.Errorf("can't to log the time (%v)", err)
+	}
+	return nil
+}
+
+// SearchTimeEntries return the time entries of a task, latest first.
+func SearchTimeEntries(tenant string, taskID string) ([]*TimeEntry, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "timeEntries")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	entries := []*TimeEntry{}
+	if err := c.Find(bson.M{"task": taskID}).Sort("-startedAt").All(&entries); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+	return entries, nil
+}
+
+// removeTimeEntries remove the time entries of deleted tasks.
+func removeTimeEntries(db *mgo.Database, taskIDs []string) error {
+	_, err := db.C("timeEntries").RemoveAll(bson.M{"task": bson.M{"$in": taskIDs}})
+	return err
+}
+
+// TimeReport sum the time logged between from and to by task, tag or list
+// on the tasks visible to the user. A task with several tags counts for each
+// of them.
+func TimeReport(tenant string, user string, from time.Time, to time.Time, by string) ([]*TimeReportRow, error) {
+	if by != ReportByTask && by != ReportByTag && by != ReportByList {
+		return nil, fmt.Errorf("report can be by %v, %v or %v", ReportByTask, ReportByTag, ReportByList)
+	}
+
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "timeEntries")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	var entries []*TimeEntry
+	query := bson.M{"startedAt": bson.M{"$gte": from, "$lt": to}, "endedAt": bson.M{"$exists": true}}
+	if err := c.Find(query).All(&entries); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+
+	var ids []bson.ObjectId
+	for _, e := range entries {
+		if bson.IsObjectIdHex(e.TaskID) {
+			ids = append(ids, bson.ObjectIdHex(e.TaskID))
+		}
+	}
+	var tasks []*Task
+	visible, err := visibleTo(c.Database, bson.M{"_id": bson.M{"$in": ids}}, user)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Database.C("tasks").Find(visible).Select(bson.M{"sid": 1, "title": 1, "tags": 1, "list": 1}).All(&tasks); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+	byID := map[string]*Task{}
+	for _, t := range tasks {
+		byID[t.SID] = t
+	}
+
+	rows := map[string]*TimeReportRow{}
+	add := func(key string, e *TimeEntry) {
+		r, ok := rows[key]
+		if !ok {
+			r = &TimeReportRow{Key: key}
+			rows[key] = r
+		}
+		r.Minutes += e.Minutes
+		r.Entries++
+	}
+	for _, e := range entries {
+		t, ok := byID[e.TaskID]
+		if !ok {
+			continue
+		}
+		switch by {
+		case ReportByTask:
+			add(t.SID, e)
+		case ReportByList:
+			add(t.ListID, e)
+		case ReportByTag:
+			if len(t.TagIDs) == 0 {
+				add("", e)
+			}
+			for _, tag := range t.TagIDs {
+				add(tag, e)
+			}
+		}
+	}
+
+	if err := nameReportRows(c.Database, rows, byID, by); err != nil {
+		return nil, err
+	}
+
+	report := []*TimeReportRow{}
+	for _, r := range rows {
+		report = append(report, r)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Minutes != report[j].Minutes {
+			return report[i].Minutes > report[j].Minutes
+		}
+		return report[i].Key < report[j].Key
+	})
+	return report, nil
+}
+
+// nameReportRows fill the name of the tasks, tags or lists of the report,
+// the row of the entries without tag or list is named none.
+func nameReportRows(db *mgo.Database, rows map[string]*TimeReportRow, tasks map[string]*Task, by string) error {
+	var ids []bson.ObjectId
+	for key := range rows {
+		if bson.IsObjectIdHex(key) {
+			ids = append(ids, bson.ObjectIdHex(key))
+		}
+	}
+
+	names := map[string]string{}
+	switch by {
+	case ReportByTask:
+		for id, t := range tasks {
+			names[id] = t.Title
+		}
+	case ReportByTag:
+		var tags []*Tag
+		if err := db.C("tags").Find(bson.M{"_id": bson.M{"$in": ids}}).All(&tags); err != nil {
+			return err
+		}
+		for _, t := range tags {
+			names[t.SID] = t.Name
+		}
+	case ReportByList:
+		var lists []*List
+		if err := db.C("lists").Find(bson.M{"_id": bson.M{"$in": ids}}).All(&lists); err != nil {
+			return err
+		}
+		for _, l := range lists {
+			names[l.SID] = l.Title
+		}
+	}
+
+	for key, r := range rows {
+		if key == "" {
+			r.Name = "none"
+			continue
+		}
+		r.Name = names[key]
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+	mgo "gopkg.in/mgo.v2"
+)
+
+// writeTimeError write the error of a time tracking request.
+func writeTimeError(w http.ResponseWriter, title string, err error) {
+	switch err {
+	case ErrNoMinutes:
+		writeError(w, http.StatusBadRequest, title, err.Error())
+	case ErrTimerRunning, ErrNoTimer:
+		writeError(w, http.StatusConflict, title, err.Error())
+	case mgo.ErrNotFound:
+		writeError(w, http.StatusNotFound, title, "task not found")
+	default:
+		writeError(w, http.StatusInternalServerError, title, err.Error())
+	}
+}
+
+// timerAPI start or stop the timer of the calling user on the task.
+func timerAPI(w http.ResponseWriter, r *http.Request, timer func(tenant string, taskID string, user string) (*TimeEntry, error), status int) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Timer Error"); err != nil {
+		return
+	}
+
+	user := RequestUser(r)
+	if user == "" {
+		writeError(w, http.StatusUnauthorized, "Timer Error", "a user is required to track time")
+		return
+	}
+
+	entry, err := timer(RequestTenant(r), mux.Vars(r)["sid"], user)
+	if err != nil {
+		writeTimeError(w, "Timer Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(status)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, entry)
+}
+
+// StartTimerAPI start a timer of the calling user on the task.
+func StartTimerAPI(w http.ResponseWriter, r *http.Request) {
+	timerAPI(w, r, StartTimer, http.StatusCreated)
+}
+
+// StopTimerAPI stop the timer of the calling user on the task.
+func StopTimerAPI(w http.ResponseWriter, r *http.Request) {
+	timerAPI(w, r, StopTimer, http.StatusOK)
+}
+
+// CreateTimeEntryAPI log some time of the calling user on the task.
+func CreateTimeEntryAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleEditor, "Save Error"); err != nil {
+		return
+	}
+
+	user := RequestUser(r)
+	if user == "" {
+		writeError(w, http.StatusUnauthorized, "Save Error", "a user is required to track time")
+		return
+	}
+
+	entry := new(TimeEntry)
+	if err := populateModel(r.Body, w, entry); err != nil {
+		return
+	}
+	entry.TaskID = mux.Vars(r)["sid"]
+	entry.User = user
+
+	if err := validateModel(entry, w); err != nil {
+		return
+	}
+
+	// Save the entry.
+	if err := entry.Save(RequestTenant(r)); err != nil {
+		writeTimeError(w, "Save Error", err)
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, entry)
+}
+
+// SearchTimeEntryAPI return the time entries of a task.
+func SearchTimeEntryAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	if err := authorizeTask(w, r, mux.Vars(r)["sid"], RoleViewer, "Search Error"); err != nil {
+		return
+	}
+
+	entries, err := SearchTimeEntries(RequestTenant(r), mux.Vars(r)["sid"])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, entries, len(entries))
+}
+
+// parseReportDate read a YYYY-MM-DD date of the report range.
+func parseReportDate(s string) (time.Time, error) {
+	return time.Parse("2006-01-02", s)
+}
+
+// TimeReportAPI return the time logged by task, tag or list between the from
+// and to dates, the last 30 days by default. The to date is included. The
+// report is written as CSV with format=csv.
+func TimeReportAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	// Get all params.
+	v := r.URL.Query()
+
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if t := v.Get("to"); t != "" {
+		d, err := parseReportDate(t)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", "to must be a date")
+			return
+		}
+		to = d.AddDate(0, 0, 1)
+	}
+
+	from := to.AddDate(0, 0, -30)
+	if f := v.Get("from"); f != "" {
+		d, err := parseReportDate(f)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Query Parameter Error", "from must be a date")
+			return
+		}
+		from = d
+	}
+
+	by := v.Get("by")
+	if by == "" {
+		by = ReportByTask
+	}
+	if by != ReportByTask && by != ReportByTag && by != ReportByList {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", "by must be task, tag or list")
+		return
+	}
+
+	format := v.Get("format")
+	if format != "" && format != "csv" {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", "format must be csv")
+		return
+	}
+
+	rows, err := TimeReport(RequestTenant(r), accessUser(r), from, to, by)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Report Error", err.Error())
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename=time-report.csv")
+		w.WriteHeader(http.StatusOK)
+
+		cw := csv.NewWriter(w)
+		cw.Write([]string{by, "name", "minutes", "entries"})
+		for _, row := range rows {
+			cw.Write([]string{row.Key, row.Name, strconv.Itoa(row.Minutes), strconv.Itoa(row.Entries)})
+		}
+		cw.Flush()
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	jsonapi.MarshalManyPayload(w, rows, len(rows))
+}
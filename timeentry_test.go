@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTimer(t *testing.T) {
+	task := createTaskOrFatal(t, "test timer")
+
+	if _, err := StopTimer("", task.SID, "alice"); err != ErrNoTimer {
+		t.Errorf("expected error %v, got %v", ErrNoTimer, err)
+	}
+
+	if _, err := StartTimer("", task.SID, "alice"); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if _, err := StartTimer("", task.SID, "alice"); err != ErrTimerRunning {
+		t.Errorf("expected error %v, got %v", ErrTimerRunning, err)
+	}
+
+	// Another user has its own timer.
+	if _, err := StartTimer("", task.SID, "bob"); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	e, err := StopTimer("", task.SID, "alice")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if e.EndedAt == nil {
+		t.Errorf("expected the entry to be ended")
+	}
+}
+
+func TestConcurrentTimers(t *testing.T) {
+	task := createTaskOrFatal(t, "test concurrent timers")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := StartTimer("", task.SID, "alice")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	started := 0
+	for err := range errs {
+		if err == nil {
+			started++
+		} else if err != ErrTimerRunning {
+			t.Errorf("expected error %v, got %v", ErrTimerRunning, err)
+		}
+	}
+	if started != 1 {
+		t.Errorf("expected a single running timer, got %v", started)
+	}
+}
+
+func TestManualTimeEntry(t *testing.T) {
+	task := createTaskOrFatal(t, "test manual time entry")
+
+	for _, minutes := range []int{30, 45} {
+		e := &TimeEntry{TaskID: task.SID, User: "alice", Minutes: minutes}
+		if err := e.Save(""); err != nil {
+			t.Fatalf("unexpected error : %v", err)
+		}
+	}
+
+	r := selectTaskOrFatal(t, task.SID)
+	if r.LoggedMinutes != 75 {
+		t.Errorf("expected 75 logged minutes, got %v", r.LoggedMinutes)
+	}
+
+	entries, err := SearchTimeEntries("", task.SID)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 entries, got %v", len(entries))
+	}
+}
+
+func TestCreateTimeEntryAPIWithoutMinutes(t *testing.T) {
+	task := newTaskOrFatal(t, "test time entry api without minutes")
+	task.CreatedByID = "alice"
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	m := mux.NewRouter()
+	m.HandleFunc("/task/{sid}/time-entries", CreateTimeEntryAPI)
+
+	rr := httptest.NewRecorder()
+	body := `{"data": {"type": "time-entry", "attributes": {"minutes": 0}}}`
+	req, _ := http.NewRequest(http.MethodPost, "/task/"+task.SID+"/time-entries", strings.NewReader(body))
+	m.ServeHTTP(rr, WithUser(req, "alice"))
+
+	// Test status code.
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestTimeReportByTag(t *testing.T) {
+	tag := createTagOrFatal(t, "test-time-report")
+	task, _ := NewTask("test time report by tag")
+	task.Tags = []*Tag{tag}
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	e := &TimeEntry{TaskID: task.SID, User: "alice", Minutes: 20}
+	if err := e.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	from := time.Now().Add(-24 * time.Hour)
+	rows, err := TimeReport("", "", from, time.Now().Add(time.Hour), ReportByTag)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	for _, r := range rows {
+		if r.Key == tag.SID {
+			if r.Minutes != 20 || r.Name != tag.Name {
+				t.Errorf("expected 20 minutes for %v, got %v for %v", tag.Name, r.Minutes, r.Name)
+			}
+			return
+		}
+	}
+	t.Errorf("expected a row for the tag %v", tag.SID)
+}
+
+func TestTimeReportAPICSV(t *testing.T) {
+	task := createTaskOrFatal(t, "test time report csv")
+	e := &TimeEntry{TaskID: task.SID, User: "alice", Minutes: 10}
+	if err := e.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	m := mux.NewRouter()
+	m.HandleFunc("/reports/time", TimeReportAPI)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/reports/time?by=task&format=csv", nil)
+
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+	if !strings.HasPrefix(rr.Body.String(), "task,name,minutes,entries\n") {
+		t.Errorf("expected a CSV header, got %v", rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), task.SID+",test time report csv,10,1") {
+		t.Errorf("expected a row for the task, got %v", rr.Body.String())
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertReloader serve a certificate reloaded from its files, the renewed
+// certificates are used without restarting.
+type CertReloader struct {
+	CertFile string
+	KeyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader load the certificate and the key of the files.
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	cr := &CertReloader{CertFile: certFile, KeyFile: keyFile}
+	if err := cr.Reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// modified return the last change of the certificate or the key.
+func (cr *CertReloader) modified() (time.Time, error) {
+	var last time.Time
+	for _, path := range []string{cr.CertFile, cr.KeyFile} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return last, err
+		}
+		if fi.ModTime().After(last) {
+			last = fi.ModTime()
+		}
+	}
+	return last, nil
+}
+
+// Reload read the files again, the current certificate is kept when the new
+// one isn't valid.
+func (cr *CertReloader) Reload() error {
+	modTime, err := cr.modified()
+	if err != nil {
+		return fmt.Errorf("can't to read the certificate (%v)", err)
+	}
+	cert, err := tls.LoadX509KeyPair(cr.CertFile, cr.KeyFile)
+	if err != nil {
+		return fmt.Errorf("can't to load the certificate (%v)", err)
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.cert = &cert
+	cr.modTime = modTime
+	return nil
+}
+
+// GetCertificate return the current certificate, it is the GetCertificate of
+// the TLS config.
+func (cr *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+// Watch reload the certificate when its files change, the files are checked
+// at each interval until stop is closed.
+func (cr *CertReloader) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		modTime, err := cr.modified()
+		cr.mu.RLock()
+		changed := err == nil && modTime.After(cr.modTime)
+		cr.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := cr.Reload(); err != nil {
+			log.Printf("tls: %v", err)
+		} else {
+			log.Printf("tls: certificate %v reloaded", cr.CertFile)
+		}
+	}
+}
+
+// NewTLSConfig create the TLS config of the server with HTTP/2. The client
+// certificates signed by the CA file are verified when given, the requests
+// without certificate use the other credentials.
+func NewTLSConfig(cr *CertReloader, clientCA string) (*tls.Config, error) {
+	config := &tls.Config{
+		GetCertificate: cr.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if clientCA == "" {
+		return config, nil
+	}
+
+	b, err := ioutil.ReadFile(clientCA)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the client CA %v (%v)", clientCA, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("client CA %v have no certificate", clientCA)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	return config, nil
+}
+
+// ClientCertAuth authenticate the verified client certificates. The common
+// name is mapped to a user created on its first request and the first
+// organizational unit is the tenant.
+type ClientCertAuth struct{}
+
+// Authenticate return the identity of the client certificate with the task
+// scopes, the requests without certificate are left to the next
+// authenticators.
+func (ClientCertAuth) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil, ErrNoCredentials
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	tenant := ""
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		tenant = cert.Subject.OrganizationalUnit[0]
+	}
+	if _, err := lookupTenant(tenant); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	email := ""
+	if len(cert.EmailAddresses) > 0 {
+		email = cert.EmailAddresses[0]
+	}
+	user, err := userForSubject(tenant, "x509:"+cert.Subject.CommonName, cert.Subject.CommonName, email)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{UserID: user.SID, Tenant: tenant, Scopes: []string{ScopeTasksWrite}}, nil
+}
+
+// RedirectHTTPS redirect the plain HTTP requests to the HTTPS port.
+func RedirectHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertOrFatal write a self-signed certificate and its key in the
+// directory.
+func writeCertOrFatal(t *testing.T, dir string, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), 0600); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return certFile, keyFile
+}
+
+// certName return the common name of the certificate served by the reloader.
+func certName(t *testing.T, cr *CertReloader) string {
+	cert, _ := cr.GetCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeCertOrFatal(t, dir, "old.example.com")
+	cr, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	writeCertOrFatal(t, dir, "new.example.com")
+	if err := cr.Reload(); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if name := certName(t, cr); name != "new.example.com" {
+		t.Errorf("expected the new certificate, got %v", name)
+	}
+
+	// An invalid certificate keeps the current one.
+	ioutil.WriteFile(certFile, []byte("not a certificate"), 0600)
+	if err := cr.Reload(); err == nil {
+		t.Errorf("expected an error for an invalid certificate")
+	}
+	if name := certName(t, cr); name != "new.example.com" {
+		t.Errorf("expected the current certificate to be kept, got %v", name)
+	}
+}
+
+func TestClientCertAuthNoCertificate(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/task/", nil)
+	if _, err := (ClientCertAuth{}).Authenticate(req); err != ErrNoCredentials {
+		t.Errorf("expected error %v, got %v", ErrNoCredentials, err)
+	}
+
+	req.TLS = &tls.ConnectionState{}
+	if _, err := (ClientCertAuth{}).Authenticate(req); err != ErrNoCredentials {
+		t.Errorf("expected error %v, got %v", ErrNoCredentials, err)
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	for port, expected := range map[string]string{
+		"443":  "https://api.example.com/task/?page=2",
+		"8443": "https://api.example.com:8443/task/?page=2",
+	} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/task/?page=2", nil)
+		req.Host = "api.example.com:8080"
+		RedirectHTTPS(port).ServeHTTP(rr, req)
+
+		// Test status code.
+		if rr.Code != http.StatusPermanentRedirect || rr.Header().Get("Location") != expected {
+			t.Errorf("Code : %v, Location : %v", rr.Code, rr.Header().Get("Location"))
+		}
+	}
+}
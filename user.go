@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// User is a person using the API, tasks are scoped to their creator and
+// assignee.
+type User struct {
+	ID    bson.ObjectId `bson:"_id,omitempty"`
+	SID   string        `bson:"sid,omitempty" jsonapi:"primary,user"`
+	Name  string        `bson:"name" validate:"required,max=50" jsonapi:"attr,name"`
+	Email string        `bson:"email,omitempty" validate:"omitempty,email" jsonapi:"attr,email,omitempty"`
+	// Subject is the identifier of the user at the identity provider.
+	Subject   string    `bson:"subject,omitempty" jsonapi:"attr,subject,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" jsonapi:"attr,created_at"`
+}
+
+func init() {
+	resourceTypes["user"] = User{}
+	taskRelations["created_by"] = loadTaskCreators
+	taskRelations["assignee"] = loadTaskAssignees
+}
+
+// hidePrivate clear the email and the identity provider subject, the members
+// only see the name of the other users.
+func (u *User) hidePrivate() {
+	u.Email, u.Subject = "", ""
+}
+
+// NewUser create a new user.
+func NewUser(name string, email string) (*User, error) {
+	u := &User{Name: name, Email: email}
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Validate checks attributes's integrity.
+func (u *User) Validate() error {
+	err := validator.New().Struct(u)
+	if err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			panic(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Save persist the user into the database.
+func (u *User) Save(tenant string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "users")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// User names are unique.
+	n, err := c.Find(bson.M{"name": u.Name}).Count()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("user already exists %v", u.Name)
+	}
+
+	// Check the quota of the tenant.
+	tn, err := lookupTenant(tenant)
+	if err != nil {
+		return err
+	}
+	if err := checkQuota(c, tn.MaxUsers); err != nil {
+		return err
+	}
+
+	u.ID = bson.NewObjectId()
+	u.SID = u.ID.Hex()
+	u.CreatedAt = time.Now()
+
+	if err := c.Insert(u); err != nil {
+		return fmt.Errorf("can't to persist the user (%v)", err)
+	}
+	return nil
+}
+
+// SelectUser find a user by ID.
+func SelectUser(tenant string, id string) (*User, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "users")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, fmt.Errorf("id value is not valid (%v)", id)
+	}
+
+	u := &User{}
+	if err := c.FindId(bson.ObjectIdHex(id)).One(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SearchUsers return all the users sorted by name.
+func SearchUsers(tenant string) ([]*User, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "users")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	users := []*User{}
+	if err := c.Find(nil).Sort("name").All(&users); err != nil {
+		return nil, fmt.Errorf("unexpected error %v", err)
+	}
+	return users, nil
+}
+
+// userForSubject return the user of an identity provider subject, the user
+// is created on the first request. The subject name the user when its name
+// is already taken.
+func userForSubject(tenant string, subject string, name string, email string) (*User, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "users")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	u := &User{}
+	if err := c.Find(bson.M{"subject": subject}).One(u); err == nil {
+		return u, nil
+	} else if err != mgo.ErrNotFound {
+		return nil, err
+	}
+
+	u = &User{Name: name, Email: email, Subject: subject}
+	if u.Name == "" {
+		u.Name = subject
+	}
+	if u.Validate() != nil {
+		u.Name, u.Email = subject, ""
+	}
+	if err := u.Save(tenant); err != nil {
+		u.Name = subject
+		if err := u.Save(tenant); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// DeleteUser remove a user and unassign its tasks, the tasks it created are
+// kept. The tasks changes are recorded in the audit as done by the actor.
+func DeleteUser(tenant string, actor string, id string) error {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "users")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !bson.IsObjectIdHex(id) {
+		return fmt.Errorf("id value is not valid (%v)", id)
+	}
+
+	// Unassign the tasks first, a failure leaves the user in place.
+	if err := auditedUpdateAll(c.Database.C("tasks"), actor, bson.M{"assignee": id}, bson.M{"$unset": bson.M{"assignee": ""}}); err != nil {
+		return fmt.Errorf("can't to unassign the tasks (%v)", err)
+	}
+	return c.RemoveId(bson.ObjectIdHex(id))
+}
+
+// resolveAssignee checks the user of the assignee relationship exists and set
+// the stored assignee ID. An assignee without ID unassign the task.
+func (t *Task) resolveAssignee(tenant string) error {
+	if t.Assignee == nil {
+		return nil
+	}
+	if t.Assignee.SID == "" {
+		t.AssigneeID = ""
+		return nil
+	}
+
+	u, err := SelectUser(tenant, t.Assignee.SID)
+	if err != nil {
+		return fmt.Errorf("unknown assignee %v (%v)", t.Assignee.SID, err)
+	}
+	t.AssigneeID = u.SID
+	return nil
+}
+
+// loadUsers return the users by ID, only the name of the other users is
+// visible to the user. An empty user see every user.
+func loadUsers(tenant string, user string, ids []string) (map[string]*User, error) {
+	// Get the database connection.
+	s, c, err := getCollection(tenant, "users")
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	var oids []bson.ObjectId
+	for _, id := range ids {
+		if bson.IsObjectIdHex(id) {
+			oids = append(oids, bson.ObjectIdHex(id))
+		}
+	}
+
+	var users []*User
+	if err := c.Find(bson.M{"_id": bson.M{"$in": oids}}).All(&users); err != nil {
+		return nil, err
+	}
+	byID := map[string]*User{}
+	for _, u := range users {
+		if user != "" && u.SID != user {
+			u.hidePrivate()
+		}
+		byID[u.SID] = u
+	}
+	return byID, nil
+}
+
+// loadTaskCreators fill the created_by relationship of the tasks.
+func loadTaskCreators(tenant string, user string, tasks []*Task) error {
+	var ids []string
+	for _, t := range tasks {
+		ids = append(ids, t.CreatedByID)
+	}
+	users, err := loadUsers(tenant, user, ids)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		t.CreatedBy = users[t.CreatedByID]
+	}
+	return nil
+}
+
+// loadTaskAssignees fill the assignee relationship of the tasks.
+func loadTaskAssignees(tenant string, user string, tasks []*Task) error {
+	var ids []string
+	for _, t := range tasks {
+		ids = append(ids, t.AssigneeID)
+	}
+	users, err := loadUsers(tenant, user, ids)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		t.Assignee = users[t.AssigneeID]
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+)
+
+// CreateUserAPI create a new user with jsonapi params.
+func CreateUserAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	user := new(User)
+	if err := populateModel(r.Body, w, user); err != nil {
+		return
+	}
+
+	if err := validateModel(user, w); err != nil {
+		return
+	}
+
+	// Save the user.
+	if err := user.Save(RequestTenant(r)); err == ErrQuotaExceeded {
+		writeError(w, http.StatusForbidden, "Save Error", err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, "Save Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusCreated)
+
+	// Write the response.
+	jsonapi.MarshalOnePayload(w, user)
+}
+
+// ReadUserAPI return a user, me is the calling user.
+func ReadUserAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	params, err := ParseParams(r, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	id, err := userFilter(mux.Vars(r)["sid"], RequestUser(r))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Read Error", err.Error())
+		return
+	}
+
+	user, err := SelectUser(RequestTenant(r), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Read Error", err.Error())
+		return
+	}
+	if accessUser(r) != "" && user.SID != RequestUser(r) {
+		user.hidePrivate()
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalOnePayload(w, user)
+	})
+}
+
+// SearchUserAPI return all the users, only the admins see the email and the
+// subject of the other users.
+func SearchUserAPI(w http.ResponseWriter, r *http.Request) {
+	// Set the header content-type.
+	w.Header().Set("Content-Type", jsonapi.MediaType)
+
+	params, err := ParseParams(r, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Query Parameter Error", err.Error())
+		return
+	}
+
+	users, err := SearchUsers(RequestTenant(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Search Error", err.Error())
+		return
+	}
+	if accessUser(r) != "" {
+		for _, u := range users {
+			if u.SID != RequestUser(r) {
+				u.hidePrivate()
+			}
+		}
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusOK)
+
+	// Write the response.
+	writePayload(w, params, func(w io.Writer) error {
+		return jsonapi.MarshalManyPayload(w, users, len(users))
+	})
+}
+
+// DeleteUserAPI remove a user and return a 204 (no-content) response.
+func DeleteUserAPI(w http.ResponseWriter, r *http.Request) {
+
+	if err := DeleteUser(RequestTenant(r), RequestUser(r), mux.Vars(r)["sid"]); err != nil {
+		writeError(w, http.StatusInternalServerError, "Delete Error", err.Error())
+		return
+	}
+
+	// Set header status code.
+	w.WriteHeader(http.StatusNoContent)
+}
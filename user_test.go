@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/jsonapi"
+	"github.com/gorilla/mux"
+)
+
+func createUserOrFatal(t *testing.T, name string) *User {
+	u, err := NewUser(name, "")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if err := u.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	return u
+}
+
+func TestTaskVisibility(t *testing.T) {
+	alice := createUserOrFatal(t, "alice visibility")
+	bob := createUserOrFatal(t, "bob visibility")
+
+	task := newTaskOrFatal(t, "test task visibility")
+	task.CreatedByID = alice.SID
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	if r, _ := SelectTaskAs("", bob.SID, task.SID, nil); r.ID.Valid() {
+		t.Errorf("expected the task to be hidden to bob")
+	}
+
+	// The assignee sees the task.
+	task.Assignee = &User{SID: bob.SID}
+	if err := task.UpdateAs("", alice.SID); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if r, _ := SelectTaskAs("", bob.SID, task.SID, nil); !r.ID.Valid() || r.CreatedByID != alice.SID {
+		t.Errorf("expected the task created by alice to be visible to bob")
+	}
+
+	search := &TaskSearch{All: true, Page: 1, Limit: 10, User: bob.SID, Assignee: bob.SID}
+	tasks, n, err := search.Find()
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if n != 1 || tasks[0].SID != task.SID {
+		t.Errorf("expected the task %v, got %v tasks", task.SID, n)
+	}
+}
+
+func TestDeleteTaskNotVisible(t *testing.T) {
+	alice := createUserOrFatal(t, "alice delete")
+
+	task := createTaskOrFatal(t, "test delete task not visible")
+	if err := DeleteTaskTreeAs("", alice.SID, alice.SID, task.SID, false); err == nil {
+		t.Errorf("expected an error deleting a task not visible")
+	}
+}
+
+func TestSearchTaskAPIAssigneeMe(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/task/", SearchTaskAPI)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/task/?assignee=me", nil)
+
+	m.ServeHTTP(rr, req)
+
+	// Test status code.
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+}
+
+func TestSearchUserAPIHidePrivate(t *testing.T) {
+	alice, err := NewUser("alice private", "alice@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	alice.Subject = "alice-subject"
+	if err := alice.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	bob := createUserOrFatal(t, "bob private")
+
+	req, _ := http.NewRequest(http.MethodGet, "/users/", strings.NewReader(""))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(SearchUserAPI).ServeHTTP(rr, WithUser(req, bob.SID))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+
+	users, err := jsonapi.UnmarshalManyPayload(rr.Body, reflect.TypeOf(new(User)))
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	for _, u := range users {
+		if u := u.(*User); u.SID == alice.SID && (u.Email != "" || u.Subject != "" || u.Name != alice.Name) {
+			t.Errorf("expected only the name of the other users, got %v", u)
+		}
+	}
+}
+
+func TestReadTaskAPIIncludeCreatorHidePrivate(t *testing.T) {
+	alice, err := NewUser("alice included", "alice.included@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	alice.Subject = "alice-included-subject"
+	if err := alice.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	bob := createUserOrFatal(t, "bob included")
+
+	task := newTaskOrFatal(t, "test include creator hide private")
+	task.CreatedByID = alice.SID
+	task.Assignee = &User{SID: bob.SID}
+	if err := task.Save(""); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+
+	m := mux.NewRouter()
+	m.HandleFunc("/task/{query}", ReadTaskAPI)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/task/"+task.SID+"?include=created_by", strings.NewReader(""))
+	m.ServeHTTP(rr, WithUser(req, bob.SID))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Code : %v, Error : %v", rr.Code, rr.Body.String())
+	}
+	if body := rr.Body.String(); !strings.Contains(body, alice.Name) || strings.Contains(body, alice.Email) || strings.Contains(body, alice.Subject) {
+		t.Errorf("expected only the name of the creator, got %v", body)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// anyStatus is the transition source matching every status.
+const anyStatus = "*"
+
+// ErrInvalidTransition is returned when the workflow doesn't allow the change
+// of status.
+var ErrInvalidTransition = errors.New("status transition not allowed")
+
+// Workflow describes the task statuses and the allowed transitions.
+type Workflow struct {
+	Statuses []string `json:"statuses"`
+	// Initial is the status of a new or a reopened task.
+	Initial string `json:"initial"`
+	// Done is the status exposed as the done attribute.
+	Done string `json:"done"`
+	// Terminal is the statuses setting the completion time.
+	Terminal []string `json:"terminal"`
+	// Transitions is the reachable statuses by status, "*" for any status.
+	Transitions map[string][]string `json:"transitions"`
+}
+
+// workflow is the task workflow in use.
+var workflow = DefaultWorkflow()
+
+// DefaultWorkflow return the built-in workflow.
+func DefaultWorkflow() *Workflow {
+	return &Workflow{
+		Statuses: []string{"todo", "in_progress", "blocked", "done", "cancelled"},
+		Initial:  "todo",
+		Done:     "done",
+		Terminal: []string{"done", "cancelled"},
+		Transitions: map[string][]string{
+			"todo":        {"in_progress", "blocked", "done"},
+			"in_progress": {"todo", "blocked", "done"},
+			"blocked":     {"todo", "in_progress"},
+			"done":        {"todo"},
+			"cancelled":   {"todo"},
+			anyStatus:     {"cancelled"},
+		},
+	}
+}
+
+// LoadWorkflow read a workflow from a JSON file.
+func LoadWorkflow(path string) (*Workflow, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the workflow %v (%v)", path, err)
+	}
+
+	wf := &Workflow{}
+	if err := json.Unmarshal(b, wf); err != nil {
+		return nil, fmt.Errorf("can't decode the workflow %v (%v)", path, err)
+	}
+	if err := wf.Validate(); err != nil {
+		return nil, err
+	}
+	return wf, nil
+}
+
+// Validate checks every status referenced by the workflow is declared.
+func (wf *Workflow) Validate() error {
+	refs := append([]string{wf.Initial, wf.Done}, wf.Terminal...)
+	for from, tos := range wf.Transitions {
+		if from != anyStatus {
+			refs = append(refs, from)
+		}
+		refs = append(refs, tos...)
+	}
+
+	for _, s := range refs {
+		if !contains(wf.Statuses, s) {
+			return fmt.Errorf("workflow status %v is not declared", s)
+		}
+	}
+	return nil
+}
+
+// CanTransition checks a task can go from a status to another.
+func (wf *Workflow) CanTransition(from string, to string) bool {
+	return from == to || contains(wf.Transitions[from], to) || contains(wf.Transitions[anyStatus], to)
+}
+
+// IsTerminal checks the status ends the task.
+func (wf *Workflow) IsTerminal(status string) bool {
+	return contains(wf.Terminal, status)
+}
+
+// validateStatus reject a status out of the workflow.
+func validateStatus(fl validator.FieldLevel) bool {
+	return contains(workflow.Statuses, fl.Field().String())
+}
+
+// currentStatus return the status of a stored task, tasks saved before the
+// workflow only have the done flag.
+func (t *Task) currentStatus() string {
+	if t.Status != "" {
+		return t.Status
+	}
+	if t.Done {
+		return workflow.Done
+	}
+	return workflow.Initial
+}
+
+// applyStatus resolve the new status from the previous version of the task
+// and set the derived attributes. An explicit status wins over the done
+// attribute, changing the done attribute without the status is a transition
+// to the done or to the initial status.
+func (t *Task) applyStatus(old *Task) error {
+	from := old.currentStatus()
+	to := t.Status
+	if to == "" {
+		to = from
+		if t.Done != (from == workflow.Done) {
+			to = workflow.Initial
+			if t.Done {
+				to = workflow.Done
+			}
+		}
+	}
+
+	if !workflow.CanTransition(from, to) {
+		return ErrInvalidTransition
+	}
+
+	t.Status = to
+	t.Done = to == workflow.Done
+	t.CompletedAt = old.CompletedAt
+	if !workflow.IsTerminal(to) {
+		t.CompletedAt = nil
+	} else if !workflow.IsTerminal(from) || t.CompletedAt == nil {
+		now := time.Now()
+		t.CompletedAt = &now
+	}
+	return nil
+}
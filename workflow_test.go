@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkflowTransitions(t *testing.T) {
+	wf := DefaultWorkflow()
+	if err := wf.Validate(); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	if !wf.CanTransition("todo", "in_progress") {
+		t.Errorf("expected todo -> in_progress to be allowed")
+	}
+	if !wf.CanTransition("blocked", "cancelled") {
+		t.Errorf("expected any -> cancelled to be allowed")
+	}
+	if wf.CanTransition("blocked", "done") {
+		t.Errorf("expected blocked -> done to be rejected")
+	}
+}
+
+func TestWorkflowWithUndeclaredStatus(t *testing.T) {
+	wf := DefaultWorkflow()
+	wf.Transitions["todo"] = append(wf.Transitions["todo"], "review")
+	if err := wf.Validate(); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestApplyStatusFromDone(t *testing.T) {
+	task := &Task{Done: true}
+	if err := task.applyStatus(&Task{Status: "todo"}); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if task.Status != "done" || task.CompletedAt == nil {
+		t.Errorf("expected a completed done task, got %v %v", task.Status, task.CompletedAt)
+	}
+
+	task.Status, task.Done = "", false
+	if err := task.applyStatus(&Task{Status: "done", CompletedAt: task.CompletedAt}); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if task.Status != "todo" || task.CompletedAt != nil {
+		t.Errorf("expected a reopened task, got %v %v", task.Status, task.CompletedAt)
+	}
+}
+
+func TestApplyStatusKeepDone(t *testing.T) {
+	completed := time.Now().Add(-time.Hour)
+
+	// The done attribute is omitted when the status is resent.
+	task := &Task{Status: "done"}
+	if err := task.applyStatus(&Task{Status: "done", Done: true, CompletedAt: &completed}); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if task.Status != "done" || !task.Done || task.CompletedAt == nil || !task.CompletedAt.Equal(completed) {
+		t.Errorf("expected the task to stay done, got %v %v %v", task.Status, task.Done, task.CompletedAt)
+	}
+}
+
+func TestUpdateTaskStatus(t *testing.T) {
+	task := createTaskOrFatal(t, "test update task status")
+	if task.Status != "todo" {
+		t.Errorf("expected status todo, got %v", task.Status)
+	}
+
+	task.Status = "blocked"
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+
+	task.Status = "done"
+	if err := task.Update(""); err != ErrInvalidTransition {
+		t.Errorf("expected error %v, got %v", ErrInvalidTransition, err)
+	}
+
+	task.Status = "cancelled"
+	if err := task.Update(""); err != nil {
+		t.Fatalf("unexpected error (%v)", err)
+	}
+	if task.Done || task.CompletedAt == nil {
+		t.Errorf("expected a completed not done task, got %v %v", task.Done, task.CompletedAt)
+	}
+}